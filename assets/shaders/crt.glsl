@@ -0,0 +1,45 @@
+#version 330 core
+// crt: screen curvature, gaussian scanlines, aperture grille mask and a vignette
+
+in vec2 TexCoord;
+out vec4 FragColor;
+uniform sampler2D Source;
+uniform vec4 SourceSize;
+uniform vec4 OutputSize;
+uniform vec4 TexRect;
+
+const float curvature = 0.08;
+const float scanlineWeight = 0.45;
+const float maskStrength = 0.2;
+
+// bend the picture like the glass of a tube, returns coordinates in 0..1 of the visible part
+vec2 curve(vec2 uv) {
+	uv = uv * 2.0 - 1.0;
+	uv *= 1.0 + curvature * (uv.yx * uv.yx);
+	return uv * 0.5 + 0.5;
+}
+
+void main() {
+	vec2 lo = min(TexRect.xy, TexRect.zw);
+	vec2 hi = max(TexRect.xy, TexRect.zw);
+	vec2 uv = curve((TexCoord - lo) / (hi - lo));
+	if (uv.x < 0.0 || uv.x > 1.0 || uv.y < 0.0 || uv.y > 1.0) {
+		FragColor = vec4(0.0, 0.0, 0.0, 1.0);
+		return;
+	}
+	vec2 coord = lo + uv * (hi - lo);
+	vec3 color = texture(Source, coord).rgb;
+	// gaussian beam: bright at the center of the source line, dark between lines
+	float line = fract(coord.y * SourceSize.y) - 0.5;
+	float beam = exp(-line * line / (2.0 * scanlineWeight * scanlineWeight));
+	color *= mix(0.6, 1.15, beam);
+	// aperture grille: every output column favors red, green or blue
+	int column = int(mod(gl_FragCoord.x, 3.0));
+	vec3 mask = vec3(1.0 - maskStrength);
+	mask[column] = 1.0;
+	color *= mask;
+	// vignette
+	vec2 edge = uv * (1.0 - uv.yx);
+	color *= clamp(pow(edge.x * edge.y * 15.0, 0.25), 0.0, 1.0);
+	FragColor = vec4(color, 1.0);
+}
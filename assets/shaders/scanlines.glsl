@@ -0,0 +1,18 @@
+#version 330 core
+// scanlines: darkens the bottom of every nes line, like the gaps between the lines of a crt
+
+in vec2 TexCoord;
+out vec4 FragColor;
+uniform sampler2D Source;
+uniform vec4 SourceSize;
+uniform vec4 OutputSize;
+
+const float strength = 0.35;
+
+void main() {
+	vec3 color = texture(Source, TexCoord).rgb;
+	// position inside the current source line, 0 at its top and 1 at its bottom
+	float line = fract(TexCoord.y * SourceSize.y);
+	float dark = strength * smoothstep(0.5, 1.0, line);
+	FragColor = vec4(color * (1.0 - dark), 1.0);
+}
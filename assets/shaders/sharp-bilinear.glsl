@@ -0,0 +1,25 @@
+#version 330 core
+// sharp-bilinear: integer prescale with nearest filtering then bilinear for the remaining fraction,
+// pixels stay sharp without the uneven widths of nearest filtering at non integer scales
+#pragma filter linear
+
+in vec2 TexCoord;
+out vec4 FragColor;
+uniform sampler2D Source;
+uniform vec4 SourceSize;
+uniform vec4 OutputSize;
+uniform vec4 TexRect;
+
+void main() {
+	// how many output pixels cover a source texel
+	vec2 visible = abs(TexRect.zw - TexRect.xy) * SourceSize.xy;
+	vec2 scale = max(floor(OutputSize.xy / visible), vec2(1.0));
+	vec2 texel = TexCoord * SourceSize.xy;
+	vec2 texelFloor = floor(texel);
+	vec2 fraction = texel - texelFloor;
+	// only blend in the last output pixel of each texel
+	vec2 regionRange = 0.5 - 0.5 / scale;
+	vec2 centerDistance = fraction - 0.5;
+	vec2 blend = (centerDistance - clamp(centerDistance, -regionRange, regionRange)) * scale + 0.5;
+	FragColor = texture(Source, (texelFloor + blend) * SourceSize.zw);
+}
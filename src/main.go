@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"os"
 
 	"./constant"
@@ -15,18 +16,50 @@ func usage(exitValue int, message string) {
 		println("MESSAGE: " + message)
 	}
 	println("USAGE:")
-	println("\t" + execName + " NES_ROM_PATH")
+	println("\t" + execName + " [OPTIONS] NES_ROM_PATH")
 	println("NES_ROM_PATH " + "the path of your nes game")
+	println("OPTIONS:")
+	flag.PrintDefaults()
 	os.Exit(exitValue)
 }
 
 func main() {
-	var argc int = len(os.Args)
+	var options ui.Options = ui.NewOptions()
+	var listMappers bool
 
-	if argc != 2 {
+	flag.Usage = func() { usage(constant.ExitFailure, "") }
+	flag.StringVar(&options.Palette, "palette", "", "palette to use: the path of a .pal file (192 or 1536 bytes) or \"ntsc\"")
+	flag.StringVar(&options.Ntsc, "ntsc", "", "ntsc filter: \"composite\", \"svideo\" or \"rgb\"")
+	flag.Float64Var(&options.NtscPalette.Hue, "hue", options.NtscPalette.Hue, "hue rotation in degrees of the ntsc palette and filter")
+	flag.Float64Var(&options.NtscPalette.Saturation, "saturation", options.NtscPalette.Saturation, "saturation of the ntsc palette and filter")
+	flag.Float64Var(&options.NtscPalette.Contrast, "contrast", options.NtscPalette.Contrast, "contrast of the ntsc palette and filter")
+	flag.Float64Var(&options.NtscPalette.Brightness, "brightness", options.NtscPalette.Brightness, "brightness of the ntsc palette and filter")
+	flag.Float64Var(&options.NtscPalette.Gamma, "gamma", options.NtscPalette.Gamma, "gamma of the ntsc palette and filter")
+	flag.Func("overscan", "pixels cropped on each edge: top,bottom,left,right (default 8,8,0,0)", options.SetOverscan)
+	flag.BoolVar(&options.Display.PixelAspect, "aspect", false, "display 8:7 pixels like a tv")
+	flag.BoolVar(&options.Display.IntegerScale, "integer", false, "scale the picture by whole numbers only")
+	flag.BoolVar(&options.Display.Stretch, "stretch", false, "stretch the picture to the whole window")
+	flag.StringVar(&options.Scaler, "scaler", "", "software scaler: scale2x, scale3x, edge2x, edge3x, edge4x, xbr2x, xbr3x or xbr4x")
+	flag.Func("shaders", "comma separated glsl files of the post-process passes, e.g. assets/shaders/crt.glsl", options.SetShaders)
+	flag.StringVar(&options.Multitap, "multitap", "", "four players adapter: \"none\", \"fourscore\" (nes) or \"hori\" (famicom), default from the nes 2.0 header")
+	flag.StringVar(&options.Port2, "port2", "", "device plugged in port 2: \"controller\", \"zapper\", \"arkanoid\" or \"powerpad\", default from the nes 2.0 header")
+	flag.StringVar(&options.Expansion, "expansion", "", "famicom expansion device: \"none\", \"arkanoid\", \"trainer\" or \"keyboard\", default from the nes 2.0 header")
+	flag.StringVar(&options.Config, "config", "", "input config file (default config.json in the MyNesEmulator user config dir)")
+	flag.Float64Var(&options.FastForward, "fastforward", 0, "speed of the fast forward, e.g. 4 for x4 (default 0: as fast as possible)")
+	flag.BoolVar(&options.Vsync, "vsync", options.Vsync, "wait for the monitor refresh to show a frame, -vsync=false to turn it off")
+	flag.StringVar(&options.Patch, "patch", "", "ips, ups or bps patch applied to the rom in memory (default: the rom name with a .ips, .ups or .bps extension, if any)")
+	flag.StringVar(&options.FdsBios, "fdsbios", "", "bios of the famicom disk system, needed by the .fds and .qd disk images (default: disksys.rom next to the image)")
+	flag.BoolVar(&listMappers, "mappers", false, "list the supported mappers and exit")
+	flag.Parse()
+
+	if listMappers {
+		ui.PrintMappers()
+		os.Exit(constant.ExitSuccess)
+	}
+	if flag.NArg() != 1 {
 		usage(constant.ExitFailure, "not enought arguments")
 	}
-	if !ui.Start(os.Args[1]) {
+	if !ui.Start(flag.Arg(0), options) {
 		usage(constant.ExitFailure, "Execution error")
 	}
 }
@@ -6,19 +6,49 @@ import (
 	"github.com/hadi-ilies/MyNesEmulator/src/nes/nescomponents"
 )
 
-/**
+/*
+*
 MEMO : FIRST LETTER of struct elem DECIDE WETHER THE ELEM IS Private or public
 MAj -> public
 MIN -> private
-**/
+*
+*/
 type Nes struct {
 	bus *nescomponents.BUS
 }
 
-func NewNes(gamePath string) Nes {
-	var nes Nes = Nes{nescomponents.NewBus(nescomponents.NewCartridge(gamePath))} //load the cartridge file and insert it into the nes
+//NewNes load the game with the files of the options: the patch to apply and the BIOS of the Famicom Disk System
+func NewNes(gamePath string, options nescomponents.LoadOptions) (Nes, error) {
+	cartridge, err := nescomponents.NewCartridge(gamePath, options) //load the cartridge file
+	if err != nil {
+		return Nes{}, err
+	}
+	var nes Nes = Nes{nescomponents.NewBus(cartridge)} //insert it into the nes
+
+	return nes, nil
+}
+
+//SaveBattery save the battery-backed memory of the game next to the rom
+func (nes *Nes) SaveBattery() error {
+	return nes.bus.GetCartridge().SaveBattery()
+}
 
-	return nes
+//InsertNextDiskSide insert the next side of the disk of a Famicom Disk System game, false if the game is not on a disk
+func (nes *Nes) InsertNextDiskSide() bool {
+	mapper, ok := nes.bus.GetCartridge().Mapper.(nescomponents.DiskMapper)
+	if ok {
+		mapper.InsertNextSide()
+	}
+	return ok
+}
+
+//EjectDisk eject the disk of a Famicom Disk System game or insert it back, false if the game is not on a disk
+func (nes *Nes) EjectDisk() bool {
+	mapper, ok := nes.bus.GetCartridge().Mapper.(nescomponents.DiskMapper)
+	if ok {
+		mapper.EjectDisk()
+	}
+	return ok
 }
 
 //reset the console
@@ -35,20 +65,52 @@ func (nes *Nes) PixelBuffer() *image.RGBA {
 	return nes.bus.GetPpu().GetFront()
 }
 
+//IndexBuffer return the 9-bit (emphasis<<6 | color) value of each pixel of the last frame
+//frontends can feed it to an ntsc filter instead of using the PixelBuffer
+func (nes *Nes) IndexBuffer() []uint16 {
+	return nes.bus.GetPpu().GetFrontPixels()
+}
+
 func (nes *Nes) Step() uint64 {
 	var cpuCycles uint64 = nes.GetComponents().GetCpu().Step()
 	ppuCycles := cpuCycles * 3
+	mapper := nes.GetComponents().GetCartridge().Mapper
+	stepper, cpuClocked := mapper.(nescomponents.CpuStepper)
 	var i uint64 = 0
 	for i = 0; i < ppuCycles; i++ {
-		nes.GetComponents().GetPpu().Step()              //todo check ppu
-		nes.GetComponents().GetCartridge().Mapper.Step() //todo it depend the mapper search a fix for that, i have to test that on the other rep
+		nes.GetComponents().GetPpu().Step() //todo check ppu
+		mapper.Step()                       //todo it depend the mapper search a fix for that, i have to test that on the other rep
+	}
+	for i = 0; i < cpuCycles; i++ {
+		nes.GetComponents().GetApu().Step()
+		if cpuClocked {
+			stepper.CpuStep()
+		}
 	}
-	// for i := 0; i < cpuCycles; i++ {
-	// 	nes.APU.Step()
-	// }
 	return cpuCycles
 }
 
+//FrameRate is the number of frames per second of a ntsc console (341*262-0.5 ppu cycles per frame)
+const FrameRate = 5369318.0 / (341*262 - 0.5)
+
+//SetAudioChannel set where the apu sends its samples, nil for no sound
+func (nes *Nes) SetAudioChannel(channel chan float32) {
+	nes.bus.GetApu().SetChannel(channel)
+}
+
+//SetSampleRate set the number of samples the apu produces per second of emulated time
+func (nes *Nes) SetSampleRate(sampleRate float64) {
+	nes.bus.GetApu().SetSampleRate(sampleRate)
+}
+
+//StepFrame run the console until the ppu starts the next frame
+func (nes *Nes) StepFrame() {
+	frame := nes.bus.GetPpu().Frame
+	for nes.bus.GetPpu().Frame == frame {
+		nes.Step()
+	}
+}
+
 func (nes *Nes) Run(seconds float64) {
 	CPUFrequency := float64(1789773)
 	cycles := int(CPUFrequency * seconds)
@@ -73,3 +135,58 @@ const (
 func (nes *Nes) SetButtonToController(buttons [8]byte) {
 	nes.bus.Controller1.SetButtons(buttons)
 }
+
+//SetButtonToController2 same for the controller of the second player
+func (nes *Nes) SetButtonToController2(buttons [8]byte) {
+	nes.bus.Controller2.SetButtons(buttons)
+}
+
+//SetButtonToPlayer set the buttons of a player (0 to 3), the players 3 and 4 need a four players adapter
+func (nes *Nes) SetButtonToPlayer(player int, buttons [8]byte) {
+	nes.bus.Controllers()[player].SetButtons(buttons)
+}
+
+//SetTurboToPlayer set the buttons of a player held in turbo mode, they toggle every rate frames
+func (nes *Nes) SetTurboToPlayer(player int, turbo [8]bool, rate int) {
+	nes.bus.Controllers()[player].SetTurbo(turbo, rate)
+}
+
+//PlayMacro make the controller of a player play a sequence of buttons
+func (nes *Nes) PlayMacro(player int, steps []nescomponents.MacroStep) {
+	nes.bus.Controllers()[player].PlayMacro(steps)
+}
+
+//UseFourScore plug a nes four score in the two controller ports
+func (nes *Nes) UseFourScore() {
+	adapter := nescomponents.NewFourScore(nes.bus.Controllers())
+	nes.bus.PlugDevice(nescomponents.Port1, adapter.Port(nescomponents.Port1))
+	nes.bus.PlugDevice(nescomponents.Port2, adapter.Port(nescomponents.Port2))
+}
+
+//GetInputDevice return the input device the game expects according to its header (nescomponents.InputZapper...)
+func (nes *Nes) GetInputDevice() byte {
+	return nes.bus.GetCartridge().GetInputDevice()
+}
+
+//UseZapper plug a zapper in port 2, the frontend aims it and pulls its trigger
+func (nes *Nes) UseZapper() *nescomponents.Zapper {
+	zapper := nescomponents.NewZapper(nes.bus.GetPpu())
+	nes.bus.PlugDevice(nescomponents.Port2, zapper)
+	return zapper
+}
+
+//UseHoriAdapter plug a hori 4 players adapter in the famicom expansion port
+func (nes *Nes) UseHoriAdapter() {
+	nes.bus.PlugExpansionDevice(nescomponents.NewHoriAdapter(nes.bus.Controllers()))
+}
+
+//PlugDevice plug a device (zapper, paddle...) in a controller port instead of the standard controller,
+//the port is nescomponents.Port1 or nescomponents.Port2
+func (nes *Nes) PlugDevice(port int, device nescomponents.InputDevice) error {
+	return nes.bus.PlugDevice(port, device)
+}
+
+//PlugExpansionDevice plug a device in the famicom expansion port
+func (nes *Nes) PlugExpansionDevice(device nescomponents.ExpansionDevice) {
+	nes.bus.PlugExpansionDevice(device)
+}
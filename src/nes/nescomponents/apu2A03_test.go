@@ -0,0 +1,138 @@
+package nescomponents
+
+import (
+	"testing"
+)
+
+//newTestBus plug a NROM cartridge of 2 PRG banks in a console, $C000-$FFEF reads 1. The cpu accepts the irqs
+func newTestBus(t *testing.T) *BUS {
+	cartridge := newTestCartridge(0, 0, 2, 1)
+	mapper, err := NewMapper(cartridge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cartridge.Mapper = mapper
+	bus := NewBus(cartridge)
+	bus.cpu.I = 0
+	return bus
+}
+
+//countIRQs step the apu and count the irqs it asks the cpu for
+func countIRQs(bus *BUS, cycles int) int {
+	var count int
+
+	for i := 0; i < cycles; i++ {
+		bus.apu.Step()
+		if bus.cpu.interrupt == interruptIRQ {
+			bus.cpu.interrupt = interruptNone
+			count++
+		}
+	}
+	return count
+}
+
+//TestApuLengthCounter the length counter is loaded from the table when the channel is enabled, counts down twice
+//per frame unless halted, and is cleared when the channel is disabled
+func TestApuLengthCounter(t *testing.T) {
+	bus := newTestBus(t)
+
+	bus.CpuWrite(0x4003, 0x08) // disabled: not loaded
+	if status := bus.CpuRead(0x4015); status&1 != 0 {
+		t.Errorf("status $%02X, the length of a disabled pulse is loaded", status)
+	}
+	bus.CpuWrite(0x4015, 0x01)
+	bus.CpuWrite(0x4000, 0x00)
+	bus.CpuWrite(0x4003, 0x08) // index 1: 254
+	if bus.apu.pulse1.lengthValue != 254 {
+		t.Fatalf("length %d, want 254", bus.apu.pulse1.lengthValue)
+	}
+	for i := 0; i < 4; i++ {
+		bus.apu.stepFrameCounter()
+	}
+	if bus.apu.pulse1.lengthValue != 252 {
+		t.Errorf("length %d after a frame, want 252", bus.apu.pulse1.lengthValue)
+	}
+
+	bus.CpuWrite(0x4000, 0x20) // halted
+	for i := 0; i < 4; i++ {
+		bus.apu.stepFrameCounter()
+	}
+	if bus.apu.pulse1.lengthValue != 252 {
+		t.Errorf("length %d, the halted counter moved", bus.apu.pulse1.lengthValue)
+	}
+
+	bus.CpuWrite(0x4000, 0x00)
+	bus.CpuWrite(0x4003, 0x18) // index 3: 2
+	for i := 0; i < 4; i++ {
+		bus.apu.stepFrameCounter()
+	}
+	if status := bus.CpuRead(0x4015); status&1 != 0 {
+		t.Errorf("status $%02X, the length counter did not reach 0", status)
+	}
+
+	bus.CpuWrite(0x4003, 0x08)
+	bus.CpuWrite(0x4015, 0x00)
+	if status := bus.CpuRead(0x4015); status&1 != 0 || bus.apu.pulse1.lengthValue != 0 {
+		t.Errorf("status $%02X, the length counter is kept by a disabled pulse", status)
+	}
+}
+
+//TestApuFrameIRQ the 4 step sequence asks for an irq once per frame, unless bit 6 of $4017 inhibits it,
+//the 5 step sequence never does
+func TestApuFrameIRQ(t *testing.T) {
+	frame := 29830 // cpu cycles of the 4 steps
+	tests := []struct {
+		frameCounter byte
+		irqs         int
+	}{
+		{0x00, 2},
+		{0x40, 0},
+		{0x80, 0},
+	}
+
+	for _, test := range tests {
+		bus := newTestBus(t)
+		bus.CpuWrite(0x4017, test.frameCounter)
+		countIRQs(bus, frame) // the sequence starts at power on, not at the write
+		if irqs := countIRQs(bus, 2*frame); irqs != test.irqs {
+			t.Errorf("$4017 = $%02X: %d irqs in 2 frames, want %d", test.frameCounter, irqs, test.irqs)
+		}
+	}
+}
+
+//TestApuDmc the dmc reads its sample from the cartridge while stalling the cpu, moves its output by 2 per bit
+//and asks for an irq at the end of the sample, or plays it again when it loops
+func TestApuDmc(t *testing.T) {
+	bus := newTestBus(t)
+	bus.CpuWrite(0x4010, 0x8F) // irq, fastest rate
+	bus.CpuWrite(0x4011, 64)
+	bus.CpuWrite(0x4012, 0x00) // $C000, the byte is 1
+	bus.CpuWrite(0x4013, 0x00) // 1 byte
+	bus.CpuWrite(0x4015, 0x10)
+	if status := bus.CpuRead(0x4015); status&0x10 == 0 {
+		t.Fatalf("status $%02X, the dmc is not active", status)
+	}
+
+	irqs := countIRQs(bus, 2*8*(int(dmcTable[15])+1))
+	if irqs != 1 {
+		t.Errorf("%d irqs at the end of the sample, want 1", irqs)
+	}
+	if bus.cpu.stall != 4 {
+		t.Errorf("the cpu is stalled for %d cycles, want 4", bus.cpu.stall)
+	}
+	if status := bus.CpuRead(0x4015); status&0x10 != 0 {
+		t.Errorf("status $%02X, the dmc is still active", status)
+	}
+	if value := bus.apu.dmc.output(); value != 64+2-7*2 {
+		t.Errorf("output %d after the bits 1 then 7 times 0, want %d", value, 64+2-7*2)
+	}
+
+	bus.CpuWrite(0x4010, 0xCF) // irq and loop
+	bus.CpuWrite(0x4015, 0x10)
+	if irqs := countIRQs(bus, 10*8*(int(dmcTable[15])+1)); irqs != 0 {
+		t.Errorf("%d irqs, a looping sample has no end", irqs)
+	}
+	if status := bus.CpuRead(0x4015); status&0x10 == 0 {
+		t.Errorf("status $%02X, the looping sample stopped", status)
+	}
+}
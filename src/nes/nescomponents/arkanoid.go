@@ -0,0 +1,68 @@
+package nescomponents
+
+//range of the potentiometer, from the leftmost to the rightmost position of the knob
+//https://wiki.nesdev.com/w/index.php/Arkanoid_controller
+const (
+	arkanoidMin = 0x54
+	arkanoidMax = 0xF4
+)
+
+//ArkanoidController is the Vaus paddle of Arkanoid: a knob and a fire button
+//the nes version is plugged in port 2, the famicom one in the expansion port
+type ArkanoidController struct {
+	famicom  bool
+	position byte // knob position, 0 (left) to 255 (right)
+	fire     bool
+	latch    byte // potentiometer value latched by the strobe, shifted out msb first
+	strobe   byte
+}
+
+//NewArkanoidController arkanoid controller constructor, famicom selects the expansion port version
+func NewArkanoidController(famicom bool) *ArkanoidController {
+	return &ArkanoidController{famicom: famicom, position: 0x80}
+}
+
+//SetPosition turn the knob, 0 is the leftmost position and 255 the rightmost one
+func (paddle *ArkanoidController) SetPosition(position byte) {
+	paddle.position = position
+}
+
+//SetFire press or release the fire button
+func (paddle *ArkanoidController) SetFire(pressed bool) {
+	paddle.fire = pressed
+}
+
+//Read nes: D3 is the button and D4 the potentiometer of port 2
+//famicom: D1 of $4016 is the button and D1 of $4017 the potentiometer
+func (paddle *ArkanoidController) Read(port int) byte {
+	var fire, bit byte
+
+	if paddle.fire {
+		fire = 1
+	}
+	if port == Port2 {
+		// the bits are inverted
+		bit = (^paddle.latch >> 7) & 1
+		if paddle.strobe == 0 {
+			paddle.latch <<= 1
+		}
+	}
+	if paddle.famicom {
+		if port == Port1 {
+			return fire << 1
+		}
+		return bit << 1
+	}
+	if port == Port1 {
+		return 0
+	}
+	return fire<<3 | bit<<4
+}
+
+//Write the strobe latches the potentiometer
+func (paddle *ArkanoidController) Write(value byte) {
+	paddle.strobe = value & 1
+	if paddle.strobe == 1 {
+		paddle.latch = byte(arkanoidMin + int(paddle.position)*(arkanoidMax-arkanoidMin)/255)
+	}
+}
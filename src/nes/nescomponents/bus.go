@@ -17,8 +17,14 @@ type BUS struct {
 	cpu          *CPU
 	cpuRam       [2048]byte //fake ram
 	ppu          *PPU
+	apu          *APU
 	cartridge    *Cartridge
-	Controller1  *Controller
+	Controller1  *Controller // standard controller of player 1, plugged in port 1 by default
+	Controller2  *Controller // standard controller of player 2, plugged in port 2 by default
+	Controller3  *Controller // players 3 and 4, only read through a four players adapter
+	Controller4  *Controller
+	ports        [2]InputDevice  // devices plugged in the controller ports
+	expansion    ExpansionDevice // device plugged in the famicom expansion port, nil if none
 	mapper       *Mapper
 	clockCounter uint //nb clock
 }
@@ -28,10 +34,17 @@ func NewBus(cartridge *Cartridge) *BUS {
 	var bus BUS
 
 	bus.cartridge = cartridge
+	bus.cartridge.bus = &bus
 	bus.mapper = &cartridge.Mapper
 	bus.cpu = NewCpu(&bus)
 	bus.ppu = NewPpu(&bus)
+	bus.apu = NewApu(&bus)
 	bus.Controller1 = NewController()
+	bus.Controller2 = NewController()
+	bus.Controller3 = NewController()
+	bus.Controller4 = NewController()
+	bus.ports[Port1] = bus.Controller1
+	bus.ports[Port2] = bus.Controller2
 	//bus.clockCounter = 0
 	return &bus
 }
@@ -42,19 +55,20 @@ func (bus *BUS) CpuWrite(address uint16, data byte) {
 		bus.cpuRam[address%0x0800] = data
 	} else if address > 0x1FFF && address < 0x4000 {
 		bus.ppu.CpuWrite(0x2000+address%8, data)
-	} else if address > 0x4000 && address < 0x4014 {
-		//mem.console.APU.writeRegister(address, data)
+	} else if address >= 0x4000 && address < 0x4014 {
+		bus.apu.writeRegister(address, data)
 	} else if address == 0x4014 {
 		bus.ppu.CpuWrite(address, data)
 	} else if address == 0x4015 {
-		//mem.console.APU.writeRegister(address, data)
+		bus.apu.writeRegister(address, data)
 	} else if address == 0x4016 {
-		bus.Controller1.Write(data)
-		//mem.console.Controller2.Write data)
+		bus.writeInput(data)
 	} else if address == 0x4017 {
-		//mem.console.APU.writeRegister(address, data)
+		bus.apu.writeRegister(address, data)
 	} else if address < 0x6000 {
-		// TODO: I/O registers
+		if mapper, ok := bus.cartridge.Mapper.(ExpansionMapper); ok && address >= 0x4020 {
+			mapper.WriteExpansion(address, data)
+		}
 	} else if address >= 0x6000 {
 		bus.cartridge.Mapper.Write(address, data)
 	} else {
@@ -73,13 +87,15 @@ func (bus *BUS) CpuRead(address uint16) byte {
 	} else if address == 0x4014 {
 		data = bus.ppu.CpuRead(address)
 	} else if address == 0x4015 {
-		//data = mem.console.APU.readRegister(address)
+		data = bus.apu.readRegister(address)
 	} else if address == 0x4016 {
-		data = bus.Controller1.Read()
+		data = bus.readInput(Port1)
 	} else if address == 0x4017 {
-		//data = mem.console.Controller2.Read()
+		data = bus.readInput(Port2)
 	} else if address < 0x6000 {
-		// TODO: I/O registers
+		if mapper, ok := bus.cartridge.Mapper.(ExpansionMapper); ok && address >= 0x4020 {
+			data = mapper.ReadExpansion(address)
+		}
 	} else if address >= 0x6000 {
 		return bus.cartridge.Mapper.Read(address) //todo check mapper
 	} else {
@@ -91,6 +107,7 @@ func (bus *BUS) CpuRead(address uint16) byte {
 //System interface
 func (bus *BUS) Reset() {
 	bus.cpu.reset() //reset cpu flags and clocks
+	bus.apu.reset()
 	//bus.clockCounter = 0 // nb clock useless
 }
 
@@ -100,7 +117,9 @@ func (bus *BUS) Clock() {
 
 func (bus *BUS) InsertCartridge(cartridge *Cartridge) {
 	bus.cartridge = cartridge
+	bus.cartridge.bus = bus
 	bus.ppu.ConnectCartridge(cartridge)
+	bus.apu.connectCartridge(cartridge)
 }
 
 /*getter*/
@@ -113,6 +132,10 @@ func (bus *BUS) GetPpu() *PPU {
 	return bus.ppu
 }
 
+func (bus *BUS) GetApu() *APU {
+	return bus.apu
+}
+
 func (bus *BUS) GetCartridge() *Cartridge {
 	return bus.cartridge
 }
@@ -1,9 +1,14 @@
 package nescomponents
 
 import (
+	"bytes"
 	"encoding/binary"
+	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 //Comunication with main BUS
@@ -51,11 +56,190 @@ type Cartridge struct {
 	chr        []byte // CHR-ROM banks
 	sram       []byte // Save RAM
 	mapperType byte   // mapper type
+	submapper  byte   // NES 2.0 submapper, 0 if the header does not say
 	mirror     byte   // mirroring mode
 	battery    byte   // battery present
+	path       string // rom file, or the patch applied to it: the battery-backed memory is saved next to it
+	input      byte   // default input device, InputUnspecified if the header does not say
+	region     byte   // RegionNtsc, RegionPal, RegionMulti or RegionDendy, only NTSC is emulated
+	bus        *BUS   // console the cartridge is plugged in, for the mappers with an irq
 }
 
-func NewCartridge(filename string) *Cartridge {
+//TriggerIRQ let the mapper interrupt the cpu, the mappers with a pending irq call it on every cpu cycle
+func (cartridge *Cartridge) TriggerIRQ() {
+	if cartridge.bus != nil {
+		cartridge.bus.cpu.triggerIRQ()
+	}
+}
+
+//savePath return the file of the battery-backed memory: the rom with the .sav extension. The changes to a disk
+//are an IPS patch of the image, not a memory dump: they get the .fdsdiff extension
+func (cartridge *Cartridge) savePath() string {
+	extension := ".sav"
+	if _, ok := cartridge.Mapper.(DiskMapper); ok {
+		extension = ".fdsdiff"
+	}
+	return strings.TrimSuffix(cartridge.path, filepath.Ext(cartridge.path)) + extension
+}
+
+//batteryMemory return the memories kept by the battery: the PRG-RAM then the memory of the mapper
+func (cartridge *Cartridge) batteryMemory() [][]byte {
+	memory := [][]byte{cartridge.sram}
+
+	if mapper, ok := cartridge.Mapper.(BatteryMapper); ok {
+		memory = append(memory, mapper.BatteryRAM())
+	}
+	return memory
+}
+
+//loadBattery restore the battery-backed memory saved by the last game, if any, or the changes to the disk
+func (cartridge *Cartridge) loadBattery() error {
+	data, err := ioutil.ReadFile(cartridge.savePath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if mapper, ok := cartridge.Mapper.(DiskMapper); ok {
+		return mapper.ApplyDiskChanges(data)
+	}
+	for _, memory := range cartridge.batteryMemory() {
+		data = data[copy(memory, data):]
+	}
+	return nil
+}
+
+//SaveBattery save the battery-backed memory of the game, nothing is done if the cartridge has no battery.
+//For a disk, it saves the changes to the disk image as an IPS patch, the image itself is never written
+func (cartridge *Cartridge) SaveBattery() error {
+	if cartridge.battery == 0 {
+		return nil
+	}
+	if mapper, ok := cartridge.Mapper.(DiskMapper); ok {
+		changes := mapper.DiskChanges()
+		if changes == nil {
+			return nil
+		}
+		return ioutil.WriteFile(cartridge.savePath(), changes, 0644)
+	}
+	var data []byte
+	for _, memory := range cartridge.batteryMemory() {
+		data = append(data, memory...)
+	}
+	return ioutil.WriteFile(cartridge.savePath(), data, 0644)
+}
+
+//the accessors of the cartridge for the mappers written outside of this package, see RegisterMapper
+
+//GetPrg return the PRG-ROM
+func (cartridge *Cartridge) GetPrg() []byte {
+	return cartridge.prg
+}
+
+//GetChr return the CHR-ROM, or the CHR-RAM when the rom has none
+func (cartridge *Cartridge) GetChr() []byte {
+	return cartridge.chr
+}
+
+//GetSram return the PRG-RAM, mapped at $6000-$7FFF by most mappers and kept by the battery if any
+func (cartridge *Cartridge) GetSram() []byte {
+	return cartridge.sram
+}
+
+//GetMapperType return the iNES mapper number
+func (cartridge *Cartridge) GetMapperType() byte {
+	return cartridge.mapperType
+}
+
+//GetSubmapper return the NES 2.0 submapper, 0 if the header does not say
+func (cartridge *Cartridge) GetSubmapper() byte {
+	return cartridge.submapper
+}
+
+//SetMirror select the mirroring of the nametables: MirrorHorizontal, MirrorVertical, MirrorSingle0...
+func (cartridge *Cartridge) SetMirror(mirror byte) {
+	cartridge.mirror = mirror
+}
+
+//GetInputDevice return the input device the game expects (InputZapper, InputArkanoidNes...)
+func (cartridge *Cartridge) GetInputDevice() byte {
+	return cartridge.input
+}
+
+//GetRegion return the console the game is made for (RegionNtsc, RegionPal...)
+func (cartridge *Cartridge) GetRegion() byte {
+	return cartridge.region
+}
+
+//loadRom read the rom file and apply its patch: patchPath, or the patch next to the rom when patchPath is "".
+//The patched rom only lives in memory. It return the rom and the file the save is named after
+func loadRom(filename string, patchPath string) ([]byte, string, error) {
+	rom, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, filename, err
+	}
+	if patchPath == "" {
+		patchPath = findPatch(filename)
+	}
+	if patchPath == "" {
+		return rom, filename, nil
+	}
+	patch, err := ioutil.ReadFile(patchPath)
+	if err != nil {
+		return rom, filename, err
+	}
+	patched, err := ApplyPatch(rom, patch)
+	if err != nil {
+		return rom, filename, fmt.Errorf("%s not applied: %v", patchPath, err)
+	}
+	return patched, patchPath, nil
+}
+
+//LoadOptions are the files loaded with a game
+type LoadOptions struct {
+	Patch   string // IPS, UPS or BPS patch, "" to look for one next to the rom
+	FdsBios string // BIOS of the Famicom Disk System (disksys.rom) for the disk images, "" to look for it next to the image
+}
+
+//newFdsCartridge insert a disk image in the RAM adapter of the Famicom Disk System, the BIOS is the 8 KB PRG-ROM.
+//The changes to the disk are saved next to savedAs, like the battery-backed memory of a rom, changes that cannot be
+//applied to the image are an error
+func newFdsCartridge(filename string, savedAs string, image []byte, biosPath string) (*Cartridge, error) {
+	var cartridge Cartridge
+
+	if biosPath == "" {
+		biosPath = filepath.Join(filepath.Dir(filename), "disksys.rom")
+	}
+	bios, err := ioutil.ReadFile(biosPath)
+	if err == nil && len(bios) != 0x2000 {
+		err = fmt.Errorf("%d bytes instead of 8192", len(bios))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the Famicom Disk System needs its BIOS, an 8 KB file given by -fdsbios (%s: %v)", biosPath, err)
+	}
+	disk, err := loadFdsDisk(filename, image)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	cartridge.mapperType = fdsMapperType
+	cartridge.prg = bios
+	cartridge.chr = make([]byte, 0x2000)
+	cartridge.sram = make([]byte, 0x8000)
+	cartridge.mirror = MirrorHorizontal
+	cartridge.battery = 1 // the disk is saved
+	cartridge.path = savedAs
+	cartridge.Mapper = newMapperFds(&cartridge, disk)
+	if err := cartridge.loadBattery(); err != nil {
+		return nil, fmt.Errorf("%s: %v", cartridge.savePath(), err)
+	}
+	return &cartridge, nil
+}
+
+//NewCartridge load a rom, patched by options.Patch or by the patch found next to the rom if it is "",
+//or a disk image of the Famicom Disk System with its BIOS. A patch that cannot be applied, a rom shorter than its
+//header says or a mapper that is not supported is an error, the game is not loaded
+func NewCartridge(filename string, options LoadOptions) (*Cartridge, error) {
 	// call ines struct and load file
 
 	//create ines header struct
@@ -64,19 +248,24 @@ func NewCartridge(filename string) *Cartridge {
 	var err error
 
 	// open the game .nes
-	file, err := os.Open(filename)
+	rom, savedAs, err := loadRom(filename, options.Patch)
 	if err != nil {
-		println("call usage and exit")
+		return nil, err
 	}
-	defer file.Close()
+	if isFdsImage(filename, rom) {
+		return newFdsCartridge(filename, savedAs, rom, options.FdsBios)
+	}
+	file := bytes.NewReader(rom)
 	// read file header
 	sHeader = InesHeader{}
 	//insert data inside sheader
 	err = binary.Read(file, binary.LittleEndian, &sHeader)
 	if err != nil {
-		println("call usage and exit")
+		return nil, fmt.Errorf("%s: no iNES header: %v", filename, err)
+	}
+	if sHeader.Magic != INESFileMagic {
+		return nil, fmt.Errorf("%s: not an iNES rom", filename)
 	}
-	//todo check header
 
 	//get mapperId
 	cartridge.mapperType = ((sHeader.Mapper2 >> 4) << 4) | (sHeader.Mapper1 >> 4)
@@ -87,11 +276,17 @@ func NewCartridge(filename string) *Cartridge {
 	// battery-backed RAM
 	cartridge.battery = (sHeader.Mapper1 >> 1) & 1
 
+	if sHeader.IsNes2() {
+		cartridge.submapper = sHeader.PrgRamSize >> 4
+		cartridge.input = sHeader.InputDevice & 0x3F
+		cartridge.region = sHeader.Timing & 0x03
+	}
+
 	// read trainer if present (unused)
 	if sHeader.Mapper1&0x04 == 4 {
 		trainer := make([]byte, 512)
 		if _, err := io.ReadFull(file, trainer); err != nil {
-			println("call usage and exit")
+			return nil, fmt.Errorf("%s: truncated trainer: %v", filename, err)
 		}
 	}
 
@@ -100,30 +295,41 @@ func NewCartridge(filename string) *Cartridge {
 	cartridge.prg = make([]byte, int(sHeader.PrgRomChunks)*16384) //number mentioned // http://wiki.nesdev.com/w/index.php/INES // http://nesdev.com/NESDoc.pdf (page 28)
 
 	if _, err := io.ReadFull(file, cartridge.prg); err != nil {
-		println("call usage and exit")
+		return nil, fmt.Errorf("%s: truncated PRG-ROM, the header says %d KB: %v", filename, len(cartridge.prg)/1024, err)
 	}
 
 	// read chr-rom bank(s)
 
 	// provide chr-rom/ram if not in file
-	if sHeader.ChrRomChunks == 0 {
-		sHeader.ChrRomChunks = 1
-	}
-	//make funtion allow memory allocation just like malloc
-	cartridge.chr = make([]byte, int(sHeader.ChrRomChunks)*8192) //number mentioned // http://wiki.nesdev.com/w/index.php/INES // http://nesdev.com/NESDoc.pdf (page 28)
-	if _, err := io.ReadFull(file, cartridge.chr); err != nil {
-		println("call usage and exit")
+	chrRom := sHeader.ChrRomChunks != 0
+	if !chrRom {
+		cartridge.chr = make([]byte, sHeader.ChrRamBytes())
+	} else {
+		//make funtion allow memory allocation just like malloc
+		cartridge.chr = make([]byte, int(sHeader.ChrRomChunks)*8192) //number mentioned // http://wiki.nesdev.com/w/index.php/INES // http://nesdev.com/NESDoc.pdf (page 28)
+		if _, err := io.ReadFull(file, cartridge.chr); err != nil {
+			return nil, fmt.Errorf("%s: truncated CHR-ROM, the header says %d KB: %v", filename, len(cartridge.chr)/1024, err)
+		}
 	}
 
 	//sram allocation
-	cartridge.sram = make([]byte, 0x2000)
+	cartridge.sram = make([]byte, sHeader.PrgRamBytes())
+
+	//fix the header of the known bad dumps
+	cartridge.applyRomDatabase(chrRom)
 
 	//load the mapper
 
 	var maperr error
 	cartridge.Mapper, maperr = NewMapper(&cartridge)
 	if maperr != nil {
-		println("call usage and exit")
+		return nil, fmt.Errorf("mapper %d: %v", cartridge.mapperType, maperr)
+	}
+	cartridge.path = savedAs
+	if cartridge.battery != 0 {
+		if err := cartridge.loadBattery(); err != nil {
+			println(err.Error())
+		}
 	}
-	return &cartridge
+	return &cartridge, nil
 }
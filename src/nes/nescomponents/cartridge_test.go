@@ -0,0 +1,60 @@
+package nescomponents
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+//makeRom return an iNES 1.0 rom of a mapper with prgChunks 16 KB PRG-ROM banks and chrChunks 8 KB CHR-ROM banks
+func makeRom(mapperType byte, prgChunks byte, chrChunks byte) []byte {
+	rom := []byte{'N', 'E', 'S', 0x1A, prgChunks, chrChunks, mapperType << 4, mapperType & 0xF0}
+	rom = append(rom, make([]byte, 8)...)
+	return append(rom, make([]byte, int(prgChunks)*0x4000+int(chrChunks)*0x2000)...)
+}
+
+func writeRom(t *testing.T, rom []byte) string {
+	path := filepath.Join(t.TempDir(), "game.nes")
+	if err := ioutil.WriteFile(path, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+//TestUnsupportedMapper a rom of a mapper that is not registered is an error, not a cartridge without mapper
+func TestUnsupportedMapper(t *testing.T) {
+	cartridge, err := NewCartridge(writeRom(t, makeRom(4, 2, 1)), LoadOptions{})
+
+	if err == nil {
+		t.Fatalf("no error, mapper %T", cartridge.Mapper)
+	}
+	if cartridge != nil {
+		t.Errorf("a cartridge is returned with the error %v", err)
+	}
+}
+
+//TestTruncatedRom a rom shorter than its header says is an error, its banks are not filled with zeros
+func TestTruncatedRom(t *testing.T) {
+	rom := makeRom(0, 2, 1)
+	withTrainer := makeRom(0, 2, 1)
+	withTrainer[6] |= 0x04
+
+	tests := []struct {
+		name string
+		rom  []byte
+	}{
+		{"short header", rom[:10]},
+		{"not an iNES rom", append([]byte("SEN\x1a"), rom[4:]...)},
+		{"truncated trainer", withTrainer[:16+100]},
+		{"truncated PRG-ROM", rom[:16+0x4000]},
+		{"truncated CHR-ROM", rom[:len(rom)-1]},
+	}
+	for _, test := range tests {
+		if _, err := NewCartridge(writeRom(t, test.rom), LoadOptions{}); err == nil {
+			t.Errorf("%s: no error", test.name)
+		}
+	}
+	if _, err := NewCartridge(writeRom(t, rom), LoadOptions{}); err != nil {
+		t.Errorf("whole rom: %v", err)
+	}
+}
@@ -1,15 +1,32 @@
 package nescomponents
 
 //Controller is a nes controller
+//the buttons are 0 when pressed and 1 when released
 type Controller struct {
-	buttons [8]byte
-	strobe  byte
-	index   byte
+	buttons    [8]byte // what the console reads: the held buttons, plus turbo and macro
+	held       [8]byte // buttons held by the player
+	turbo      [8]bool // buttons held in turbo mode
+	turboRate  int     // frames between two toggles of the turbo buttons
+	frame      int     // frames since power on, for the turbo
+	macro      []MacroStep
+	macroFrame int // frames since the start of the macro
+	strobe     byte
+	index      byte
+}
+
+//MacroStep is a step of a macro: buttons pressed during some frames
+type MacroStep struct {
+	Buttons [8]bool // indexed like the controller buttons, true when pressed
+	Frames  int
 }
 
 //NewController Controller constructor
 func NewController() *Controller {
-	return &Controller{}
+	var controller = Controller{turboRate: 2}
+
+	controller.held = [8]byte{1, 1, 1, 1, 1, 1, 1, 1}
+	controller.update()
+	return &controller
 }
 
 //GetButton can be useful
@@ -19,24 +36,89 @@ func (controller *Controller) GetButton() [8]byte {
 
 //SetButtons allow me to set buttons throught the ui
 func (controller *Controller) SetButtons(buttons [8]byte) {
-	controller.buttons = buttons
+	controller.held = buttons
+	controller.update()
+}
+
+//SetTurbo set the buttons held in turbo mode, they toggle every rate frames
+func (controller *Controller) SetTurbo(turbo [8]bool, rate int) {
+	if rate < 1 {
+		rate = 1
+	}
+	controller.turbo = turbo
+	controller.turboRate = rate
+	controller.update()
+}
+
+//PlayMacro start a macro, it replaces the one playing
+func (controller *Controller) PlayMacro(steps []MacroStep) {
+	controller.macro = steps
+	controller.macroFrame = 0
+	controller.update()
+}
+
+//nextFrame move the turbo and the macro forward, called at the start of each vblank
+func (controller *Controller) nextFrame() {
+	controller.frame++
+	if controller.macro != nil {
+		controller.macroFrame++
+	}
+	controller.update()
+}
+
+//macroButtons return the buttons pressed by the macro during the current frame, the macro stops after its last step
+func (controller *Controller) macroButtons() [8]bool {
+	frame := controller.macroFrame
+
+	for _, step := range controller.macro {
+		if frame < step.Frames {
+			return step.Buttons
+		}
+		frame -= step.Frames
+	}
+	controller.macro = nil
+	return [8]bool{}
+}
+
+//update compute the buttons the console reads, a button is pressed if the player holds it,
+//if it is in turbo mode during the pressed half of the turbo period or if the macro presses it
+func (controller *Controller) update() {
+	turboOn := (controller.frame/controller.turboRate)%2 == 0
+	macro := controller.macroButtons()
+
+	for i := range controller.buttons {
+		pressed := controller.held[i] == 0 || (controller.turbo[i] && turboOn) || macro[i]
+		controller.buttons[i] = 1
+		if pressed {
+			controller.buttons[i] = 0
+		}
+	}
+}
+
+//bit return the state of a button (A, B, Select, Start, Up, Down, Left, Right), 1 if pressed, 0 after the 8 buttons
+func (controller *Controller) bit(index int) byte {
+	if index < 8 && controller.buttons[index] == 0 {
+		return 1
+	}
+	return 0
 }
 
+//Read shift out the next button on D0
 func (controller *Controller) Read() byte {
-	var value byte = 0
+	value := controller.bit(int(controller.index))
 
-	if controller.index < 8 && controller.buttons[controller.index] == 0 {
-		value = 1
+	if controller.index < 8 {
+		controller.index++
 	}
-	controller.index++
 	if controller.strobe == 1 {
 		controller.index = 0
 	}
 	return value
 }
 
+//Write set the strobe, while it is high the buttons are reloaded and A is read again and again
 func (controller *Controller) Write(value byte) {
-	controller.strobe = value
+	controller.strobe = value & 1
 	if controller.strobe == 1 {
 		controller.index = 0
 	}
@@ -2,7 +2,6 @@ package nescomponents
 
 import (
 	"fmt"
-	"os"
 )
 
 // pagesDiffer returns true if the two addresses reference different pages
@@ -277,6 +276,7 @@ func asl(cpu *CPU, address uint16, pc uint16, isAnAccumulator bool) {
 	} else {
 		var value byte = cpu.bus.CpuRead(address)
 
+		cpu.rmwDummyWrite(address, value)
 		cpu.C = (value >> 7) & 1
 		value <<= 1
 		cpu.bus.CpuWrite(address, value)
@@ -425,8 +425,10 @@ func cpy(cpu *CPU, address uint16, pc uint16, isAnAccumulator bool) {
 
 // DEC - Decrement Memory
 func dec(cpu *CPU, address uint16, pc uint16, isAnAccumulator bool) {
-	var value byte = cpu.bus.CpuRead(address) - 1
+	var value byte = cpu.bus.CpuRead(address)
 
+	cpu.rmwDummyWrite(address, value)
+	value--
 	cpu.bus.CpuWrite(address, value)
 	cpu.setZN(value)
 }
@@ -452,8 +454,10 @@ func eor(cpu *CPU, address uint16, pc uint16, isAnAccumulator bool) {
 
 // INC - Increment Memory
 func inc(cpu *CPU, address uint16, pc uint16, isAnAccumulator bool) {
-	var value byte = cpu.bus.CpuRead(address) + 1
+	var value byte = cpu.bus.CpuRead(address)
 
+	cpu.rmwDummyWrite(address, value)
+	value++
 	cpu.bus.CpuWrite(address, value)
 	cpu.setZN(value)
 }
@@ -511,6 +515,7 @@ func lsr(cpu *CPU, address uint16, pc uint16, isAnAccumulator bool) {
 	} else {
 		var value byte = cpu.bus.CpuRead(address)
 
+		cpu.rmwDummyWrite(address, value)
 		cpu.C = value & 1
 		value >>= 1
 		cpu.bus.CpuWrite(address, value)
@@ -566,6 +571,7 @@ func rol(cpu *CPU, address uint16, pc uint16, isAnAccumulator bool) {
 		cpu.setZN(cpu.A)
 	} else {
 		value := cpu.bus.CpuRead(address)
+		cpu.rmwDummyWrite(address, value)
 		cpu.C = (value >> 7) & 1
 		value = (value << 1) | tmp
 		cpu.bus.CpuWrite(address, value)
@@ -583,6 +589,7 @@ func ror(cpu *CPU, address uint16, pc uint16, isAnAccumulator bool) {
 		cpu.setZN(cpu.A)
 	} else {
 		value := cpu.bus.CpuRead(address)
+		cpu.rmwDummyWrite(address, value)
 		cpu.C = value & 1
 		value = (value >> 1) | (tmp << 7)
 		cpu.bus.CpuWrite(address, value)
@@ -773,6 +780,12 @@ func xaa(cpu *CPU, address uint16, pc uint16, isAnAccumulator bool) {
 
 //_________________________________________________________________________________________________________________________________
 
+// rmwDummyWrite the read-modify-write instructions write the unmodified value back the cycle before the result,
+// the mappers which ignore consecutive writes (MMC1) rely on it
+func (cpu *CPU) rmwDummyWrite(address uint16, value byte) {
+	cpu.bus.CpuWrite(address, value)
+}
+
 // setZ sets the zero flag if the argument is zero
 func (cpu *CPU) setZ(value byte) {
 	if value == 0 {
@@ -896,11 +909,10 @@ func createModesTables() map[byte]addrModes {
 	return modes
 }
 
-// triggerIRQ causes an IRQ interrupt to occur on the next cycle // NOTE : useless for now !
+// triggerIRQ causes an IRQ interrupt to occur on the next cycle, used by the apu and the mappers
 func (cpu *CPU) triggerIRQ() {
-	if cpu.I == 0 {
+	if cpu.I == 0 && cpu.interrupt != interruptNMI {
 		cpu.interrupt = interruptIRQ
-		os.Exit(5)
 	}
 }
 
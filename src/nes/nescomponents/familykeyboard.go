@@ -0,0 +1,64 @@
+package nescomponents
+
+//size of the key matrix of the family basic keyboard: 9 rows of 2 columns of 4 keys
+const (
+	FamilyKeyboardRows = 9
+	FamilyKeyboardKeys = FamilyKeyboardRows * 8
+)
+
+//FamilyKeyboard is the Family BASIC keyboard, plugged in the famicom expansion port
+//the game resets the row counter, then toggles the column select to scan the matrix 4 keys at a time
+//https://wiki.nesdev.com/w/index.php/Family_BASIC_Keyboard
+type FamilyKeyboard struct {
+	// keys pressed, indexed by row*8 + column*4 + key, the key 0 is read on D4 and the key 3 on D1
+	keys    [FamilyKeyboardKeys]bool
+	row     int
+	column  byte
+	enabled bool
+}
+
+//NewFamilyKeyboard family basic keyboard constructor
+func NewFamilyKeyboard() *FamilyKeyboard {
+	return &FamilyKeyboard{}
+}
+
+//SetKeys set the pressed keys of the matrix
+func (keyboard *FamilyKeyboard) SetKeys(keys [FamilyKeyboardKeys]bool) {
+	keyboard.keys = keys
+}
+
+//Read the 4 keys of the selected half row on D1-D4 of $4017, 0 when pressed
+func (keyboard *FamilyKeyboard) Read(port int) byte {
+	var pressed byte
+
+	if port != Port2 || !keyboard.enabled {
+		return 0
+	}
+	if keyboard.row >= FamilyKeyboardRows {
+		return 0x1E
+	}
+	for key := 0; key < 4; key++ {
+		if keyboard.keys[keyboard.row*8+int(keyboard.column)*4+key] {
+			pressed |= 1 << uint(3-key)
+		}
+	}
+	return ^pressed << 1 & 0x1E
+}
+
+//Write bit 0 goes back to the first row, bit 1 selects the column, going back to column 0 moves to the next row
+//and bit 2 enables the keyboard
+func (keyboard *FamilyKeyboard) Write(value byte) {
+	column := value >> 1 & 1
+
+	keyboard.enabled = value&0x04 != 0
+	if !keyboard.enabled {
+		return
+	}
+	if keyboard.column == 1 && column == 0 {
+		keyboard.row++
+	}
+	keyboard.column = column
+	if value&0x01 != 0 {
+		keyboard.row = 0
+	}
+}
@@ -0,0 +1,246 @@
+package nescomponents
+
+//the Famicom Disk System sound is one channel playing a wavetable of 64 samples of 6 bits, with a volume envelope and
+//a frequency modulator: a table of 32 steps moving a counter which bends the pitch of the wave, with its own envelope.
+//The envelopes are clocked every 8 x (speed + 1) x master speed cpu cycles
+//https://wiki.nesdev.com/w/index.php/FDS_audio
+const (
+	fdsOutputStep          = 0.36 / 63 // level of one step of the output, a full volume wave is 2.4 times a pulse
+	fdsDefaultMasterSpeed  = 0xE8
+	fdsModulationResetStep = 4 // entry of the modulation table which resets the counter
+)
+
+//fdsMasterVolumes the master volume (2/2, 2/3, 2/4, 2/5) applied as level x fdsMasterVolumes / 36
+var fdsMasterVolumes = [4]int{36, 24, 17, 14}
+
+//fdsModulationSteps what each entry of the modulation table adds to the counter
+var fdsModulationSteps = [8]int{0, 1, 2, 4, 0, -4, -2, -1}
+
+//fdsEnvelope is the volume envelope or the modulation envelope, its gain goes up or down one step per clock up to 32.
+//The gain can be over 32 when it is set directly, it is then limited to 32 for the volume
+type fdsEnvelope struct {
+	speed    byte
+	increase bool
+	off      bool // the gain is set directly to the speed
+	gain     byte
+	timer    int
+}
+
+// $4080/$4084: bits 0-5 speed or gain, bit 6 increase, bit 7 envelope off
+func (envelope *fdsEnvelope) write(value byte, masterSpeed byte) {
+	envelope.speed = value & 0x3F
+	envelope.increase = value&0x40 != 0
+	envelope.off = value&0x80 != 0
+	if envelope.off {
+		envelope.gain = envelope.speed
+	}
+	envelope.reset(masterSpeed)
+}
+
+func (envelope *fdsEnvelope) reset(masterSpeed byte) {
+	envelope.timer = 8 * (int(envelope.speed) + 1) * int(masterSpeed)
+}
+
+//step clock the envelope, it return true when the gain moves
+func (envelope *fdsEnvelope) step(masterSpeed byte) bool {
+	if envelope.off || masterSpeed == 0 {
+		return false
+	}
+	envelope.timer--
+	if envelope.timer > 0 {
+		return false
+	}
+	envelope.reset(masterSpeed)
+	if envelope.increase && envelope.gain < 32 {
+		envelope.gain++
+	} else if !envelope.increase && envelope.gain > 0 {
+		envelope.gain--
+	}
+	return true
+}
+
+//FdsAudio is the sound of the Famicom Disk System
+type FdsAudio struct {
+	wave            [64]byte
+	waveWrite       bool // the wave RAM is writable and the wave stops
+	wavePosition    byte
+	waveAccumulator uint32 // the position moves when it passes $FFFF
+	frequency       uint16 // 12 bits
+	haltWave        bool
+	haltEnvelopes   bool
+	volume          fdsEnvelope
+	masterVolume    byte
+	masterSpeed     byte
+	modulation      fdsEnvelope
+	modTable        [64]byte // 32 entries written twice, one per half step
+	modPosition     byte
+	modCounter      int // 7 bits, signed
+	modFrequency    uint16
+	modAccumulator  uint16
+	modHalt         bool
+	pitchModulation int // added to the frequency of the wave
+	output          byte
+}
+
+func newFdsAudio() FdsAudio {
+	return FdsAudio{masterSpeed: fdsDefaultMasterSpeed}
+}
+
+//read the wave RAM ($4040-$407F) and the gains ($4090, $4092), the bits 6-7 are open bus
+func (audio *FdsAudio) read(address uint16) byte {
+	switch {
+	case address >= 0x4040 && address <= 0x407F:
+		if !audio.waveWrite {
+			return audio.wave[audio.wavePosition] | 0x40
+		}
+		return audio.wave[address&0x3F] | 0x40
+	case address == 0x4090:
+		return audio.volume.gain | 0x40
+	case address == 0x4092:
+		return audio.modulation.gain | 0x40
+	}
+	return 0x40
+}
+
+func (audio *FdsAudio) write(address uint16, value byte) {
+	switch {
+	case address >= 0x4040 && address <= 0x407F:
+		if audio.waveWrite {
+			audio.wave[address&0x3F] = value & 0x3F
+		}
+	case address == 0x4080:
+		audio.volume.write(value, audio.masterSpeed)
+	case address == 0x4082:
+		audio.frequency = audio.frequency&0x0F00 | uint16(value)
+		audio.updateModulation()
+	case address == 0x4083:
+		// bit 7 halts the wave at its first sample, bit 6 halts the envelopes
+		audio.frequency = audio.frequency&0x00FF | uint16(value&0x0F)<<8
+		audio.haltWave = value&0x80 != 0
+		audio.haltEnvelopes = value&0x40 != 0
+		if audio.haltWave {
+			audio.wavePosition = 0
+			audio.waveAccumulator = 0
+		}
+		if audio.haltEnvelopes {
+			audio.volume.reset(audio.masterSpeed)
+			audio.modulation.reset(audio.masterSpeed)
+		}
+		audio.updateModulation()
+	case address == 0x4084:
+		audio.modulation.write(value, audio.masterSpeed)
+		audio.updateModulation()
+	case address == 0x4085:
+		audio.setModCounter(int(value & 0x7F))
+		audio.updateModulation()
+	case address == 0x4086:
+		audio.modFrequency = audio.modFrequency&0x0F00 | uint16(value)
+	case address == 0x4087:
+		// bit 7 halts the modulator, the table can then be written
+		audio.modFrequency = audio.modFrequency&0x00FF | uint16(value&0x0F)<<8
+		audio.modHalt = value&0x80 != 0
+		if audio.modHalt {
+			audio.modAccumulator = 0
+		}
+	case address == 0x4088:
+		if audio.modHalt {
+			audio.modTable[audio.modPosition] = value & 0x07
+			audio.modTable[(audio.modPosition+1)&0x3F] = value & 0x07
+			audio.modPosition = (audio.modPosition + 2) & 0x3F
+		}
+	case address == 0x4089:
+		audio.masterVolume = value & 0x03
+		audio.waveWrite = value&0x80 != 0
+	case address == 0x408A:
+		audio.masterSpeed = value
+	}
+}
+
+//setModCounter set the 7 bit signed counter of the modulator, wrapping
+func (audio *FdsAudio) setModCounter(value int) {
+	value &= 0x7F
+	if value >= 64 {
+		value -= 128
+	}
+	audio.modCounter = value
+}
+
+//updateModulation compute the pitch change of the wave from the counter and the gain of the modulator,
+//with the rounding of the hardware
+func (audio *FdsAudio) updateModulation() {
+	temp := audio.modCounter * int(audio.modulation.gain)
+	remainder := temp & 0x0F
+	temp >>= 4
+	if remainder > 0 && temp&0x80 == 0 {
+		if audio.modCounter < 0 {
+			temp--
+		} else {
+			temp += 2
+		}
+	}
+	if temp >= 192 {
+		temp -= 256
+	} else if temp < -64 {
+		temp += 256
+	}
+	temp = int(audio.frequency) * temp
+	remainder = temp & 0x3F
+	temp >>= 6
+	if remainder >= 32 {
+		temp++
+	}
+	audio.pitchModulation = temp
+}
+
+//step clock the envelopes, the modulator and the wave, once per cpu cycle
+func (audio *FdsAudio) step() {
+	if !audio.haltWave && !audio.haltEnvelopes {
+		audio.volume.step(audio.masterSpeed)
+		if audio.modulation.step(audio.masterSpeed) {
+			audio.updateModulation()
+		}
+	}
+	if !audio.modHalt && audio.modFrequency != 0 {
+		last := audio.modAccumulator
+		audio.modAccumulator += audio.modFrequency
+		if audio.modAccumulator < last { // one step of the table every $10000
+			step := audio.modTable[audio.modPosition]
+			if step == fdsModulationResetStep {
+				audio.setModCounter(0)
+			} else {
+				audio.setModCounter(audio.modCounter + fdsModulationSteps[step])
+			}
+			audio.modPosition = (audio.modPosition + 1) & 0x3F
+			audio.updateModulation()
+		}
+	}
+	if audio.haltWave {
+		audio.wavePosition = 0
+		audio.updateOutput()
+		return
+	}
+	audio.updateOutput()
+	if frequency := int(audio.frequency) + audio.pitchModulation; frequency > 0 && !audio.waveWrite {
+		audio.waveAccumulator += uint32(frequency)
+		if audio.waveAccumulator > 0xFFFF {
+			audio.waveAccumulator -= 0x10000
+			audio.wavePosition = (audio.wavePosition + 1) & 0x3F
+		}
+	}
+}
+
+//updateOutput the sample of the wave at the volume, the output holds its last value while the wave RAM is written
+func (audio *FdsAudio) updateOutput() {
+	if audio.waveWrite {
+		return
+	}
+	gain := int(audio.volume.gain)
+	if gain > 32 {
+		gain = 32
+	}
+	audio.output = byte(int(audio.wave[audio.wavePosition]) * gain * fdsMasterVolumes[audio.masterVolume] / 1152)
+}
+
+func (audio *FdsAudio) audioOutput() float32 {
+	return float32(audio.output) * fdsOutputStep
+}
@@ -0,0 +1,198 @@
+package nescomponents
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+//the disk images of the Famicom Disk System: a .fds file is the sides of 65500 bytes one after the other, after an optional
+//16 bytes header ("FDS" $1A, number of sides), a .qd file is the sides of 65536 bytes with the CRC after each block.
+//The drive also reads the gaps between the blocks, the mark starting each block and the CRCs the .fds images leave out,
+//they are added to the sides in the drive
+//https://wiki.nesdev.com/w/index.php/FDS_disk_format
+const (
+	fdsSideSize     = 65500
+	qdSideSize      = 65536
+	fdsLeadInGap    = 28300 / 8 // bytes of gap before the first block
+	fdsBlockGap     = 976 / 8   // bytes of gap after each block
+	fdsMinTrackSize = 0x12000   // bytes of a side in the drive, with the gaps, marks and CRCs
+)
+
+//fdsDisk is the sides of a disk in the .fds format, without header
+type fdsDisk struct {
+	sides [][]byte
+}
+
+//isFdsImage tell whether a file is a disk image, by its extension, the header of the .fds files or the first block of a disk
+func isFdsImage(filename string, data []byte) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".fds", ".qd":
+		return true
+	}
+	return bytes.HasPrefix(data, []byte("FDS\x1a")) || bytes.HasPrefix(data, []byte("\x01*NINTENDO-HVC*"))
+}
+
+//loadFdsDisk split an image in sides, the CRCs of the QD sides are removed
+func loadFdsDisk(filename string, data []byte) (*fdsDisk, error) {
+	if bytes.HasPrefix(data, []byte("FDS\x1a")) && len(data) >= 16 {
+		data = data[16:]
+	}
+	qd := strings.EqualFold(filepath.Ext(filename), ".qd") || (len(data)%fdsSideSize != 0 && len(data)%qdSideSize == 0)
+	sideSize := fdsSideSize
+	if qd {
+		sideSize = qdSideSize
+	}
+	disk := fdsDisk{}
+	for ; len(data) >= sideSize; data = data[sideSize:] {
+		side := make([]byte, 0, fdsSideSize)
+		for _, block := range splitBlocks(data[:sideSize], qd) {
+			side = append(side, block...)
+		}
+		if !qd {
+			side = append(side[:0], data[:fdsSideSize]...)
+		}
+		disk.sides = append(disk.sides, padSide(side))
+	}
+	if len(disk.sides) == 0 {
+		return nil, errors.New("the disk image has no side")
+	}
+	return &disk, nil
+}
+
+//image return the sides one after the other in the .fds format, without header
+func (disk *fdsDisk) image() []byte {
+	var image []byte
+
+	for _, side := range disk.sides {
+		image = append(image, side...)
+	}
+	return image
+}
+
+//padSide fill a side with zeros up to its size
+func padSide(side []byte) []byte {
+	if len(side) < fdsSideSize {
+		side = append(side, make([]byte, fdsSideSize-len(side))...)
+	}
+	return side[:fdsSideSize]
+}
+
+//blockLength return the length of the block at the start of data, with its type byte, 0 if there is no valid block.
+//The length of a file data block (4) is given by the last file header block (3)
+func blockLength(data []byte, fileSize int) int {
+	length := 0
+	if len(data) == 0 {
+		return 0
+	}
+	switch data[0] {
+	case 1: // disk info
+		length = 56
+	case 2: // file amount
+		length = 2
+	case 3: // file header
+		length = 16
+	case 4: // file data
+		length = 1 + fileSize
+	}
+	if length > len(data) {
+		return 0
+	}
+	return length
+}
+
+//fileSize return the size of the file data given by a file header block, or the last size for the other blocks
+func fileSize(block []byte, last int) int {
+	if block[0] == 3 {
+		return int(block[13]) | int(block[14])<<8
+	}
+	return last
+}
+
+//splitBlocks return the blocks of a side, each one followed by its CRC on the QD sides
+func splitBlocks(side []byte, crcs bool) [][]byte {
+	var blocks [][]byte
+	size := 0
+
+	for position := 0; position < len(side); {
+		length := blockLength(side[position:], size)
+		if length == 0 {
+			break
+		}
+		block := side[position : position+length]
+		size = fileSize(block, size)
+		blocks = append(blocks, block)
+		position += length
+		if crcs {
+			position += 2
+		}
+	}
+	return blocks
+}
+
+//fdsCrc return the CRC of a block, computed with the $80 mark starting the block
+func fdsCrc(block []byte) uint16 {
+	crc := crcByte(0, 0x80)
+
+	for _, value := range block {
+		crc = crcByte(crc, value)
+	}
+	return crcByte(crcByte(crc, 0), 0)
+}
+
+//crcByte add a byte to a CRC of the drive, bit 0 first: CRC-16 with the polynomial $8408
+func crcByte(crc uint16, value byte) uint16 {
+	for bit := uint(0); bit < 8; bit++ {
+		carry := crc & 1
+		crc = crc>>1 | uint16(value>>bit&1)<<15
+		if carry != 0 {
+			crc ^= 0x8408
+		}
+	}
+	return crc
+}
+
+//track return a side as it passes under the head of the drive: a gap, then each block after its $80 mark,
+//followed by its CRC and a gap
+func (disk *fdsDisk) track(side int) []byte {
+	track := make([]byte, fdsLeadInGap, fdsMinTrackSize)
+
+	for _, block := range splitBlocks(disk.sides[side], false) {
+		crc := fdsCrc(block)
+		track = append(track, 0x80)
+		track = append(track, block...)
+		track = append(track, byte(crc), byte(crc>>8))
+		track = append(track, make([]byte, fdsBlockGap)...)
+	}
+	if len(track) < fdsMinTrackSize {
+		track = append(track, make([]byte, fdsMinTrackSize-len(track))...)
+	}
+	return track
+}
+
+//readTrack return the side in the .fds format of a track written by the drive: the blocks found after the gaps,
+//without their marks and CRCs
+func readTrack(track []byte) []byte {
+	side := make([]byte, 0, fdsSideSize)
+	size := 0
+
+	for position := 0; ; {
+		for position < len(track) && track[position] == 0 {
+			position++
+		}
+		if position >= len(track) || track[position] != 0x80 {
+			break
+		}
+		position++
+		length := blockLength(track[position:], size)
+		if length == 0 || len(side)+length > fdsSideSize {
+			break
+		}
+		block := track[position : position+length]
+		size = fileSize(block, size)
+		side = append(side, block...)
+		position += length + 2 // CRC
+	}
+	return padSide(side)
+}
@@ -0,0 +1,198 @@
+package nescomponents
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//makeFdsSide build a side in the .fds format: the disk info block, a file amount of 1, the header and the data of a file
+func makeFdsSide(name string, data []byte) []byte {
+	side := append([]byte{1}, "*NINTENDO-HVC*"...)
+	side = append(side, bytes.Repeat([]byte{0x11}, 56-len(side))...)
+	side = append(side, 2, 1)
+	header := make([]byte, 16)
+	header[0] = 3
+	copy(header[3:], name)
+	header[13] = byte(len(data))
+	header[14] = byte(len(data) >> 8)
+	side = append(side, header...)
+	side = append(side, 4)
+	side = append(side, data...)
+	return padSide(side)
+}
+
+//makeFdsImage return a 2 sides image with the 16 bytes header
+func makeFdsImage() []byte {
+	image := append([]byte("FDS\x1a\x02"), make([]byte, 11)...)
+	image = append(image, makeFdsSide("FILEA", []byte("hello disk A"))...)
+	return append(image, makeFdsSide("FILEB", bytes.Repeat([]byte{0xAB}, 300))...)
+}
+
+//TestFdsCrc the drive computes a CRC-16/KERMIT with the data shifted in, the 2 zeros flushing it: the standard check
+//value, and a block followed by its CRC leaves nothing
+func TestFdsCrc(t *testing.T) {
+	crc := uint16(0)
+	for _, value := range []byte("123456789") {
+		crc = crcByte(crc, value)
+	}
+	if crc = crcByte(crcByte(crc, 0), 0); crc != 0x2189 {
+		t.Errorf("CRC of \"123456789\" $%04X, want $2189", crc)
+	}
+
+	for _, block := range splitBlocks(makeFdsSide("FILEA", []byte("hello disk A")), false) {
+		crc := fdsCrc(block)
+		residue := crcByte(0, 0x80)
+		for _, value := range append(append([]byte{}, block...), byte(crc), byte(crc>>8)) {
+			residue = crcByte(residue, value)
+		}
+		if residue != 0 {
+			t.Errorf("block %d followed by its CRC leaves $%04X", block[0], residue)
+		}
+	}
+}
+
+func TestFdsImageFormats(t *testing.T) {
+	image := makeFdsImage()
+	sides := [][]byte{image[16 : 16+fdsSideSize], image[16+fdsSideSize:]}
+	var qd []byte
+	for _, side := range sides {
+		var qdSide []byte
+		for _, block := range splitBlocks(side, false) {
+			crc := fdsCrc(block)
+			qdSide = append(append(qdSide, block...), byte(crc), byte(crc>>8))
+		}
+		qd = append(qd, append(qdSide, make([]byte, qdSideSize-len(qdSide))...)...)
+	}
+
+	for name, data := range map[string][]byte{"game.fds": image, "game.bin": image[16:], "game.qd": qd} {
+		if !isFdsImage(name, data) {
+			t.Errorf("%s: not taken for a disk image", name)
+		}
+		disk, err := loadFdsDisk(name, data)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if len(disk.sides) != 2 || !bytes.Equal(disk.sides[0], sides[0]) || !bytes.Equal(disk.sides[1], sides[1]) {
+			t.Errorf("%s: the sides are not the ones of the image", name)
+		}
+	}
+	if isFdsImage("game.nes", []byte("NES\x1a")) {
+		t.Errorf("a rom is taken for a disk image")
+	}
+}
+
+//TestFdsTrack a side goes to the drive with its gaps, marks and CRCs, and comes back the same
+func TestFdsTrack(t *testing.T) {
+	disk, err := loadFdsDisk("game.fds", makeFdsImage())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for side := range disk.sides {
+		track := disk.track(side)
+		if len(track) != fdsMinTrackSize {
+			t.Errorf("side %d: track of %d bytes, want %d", side, len(track), fdsMinTrackSize)
+		}
+		first := splitBlocks(disk.sides[side], false)[0]
+		crc := fdsCrc(first)
+		start := fdsLeadInGap
+		end := start + 1 + len(first)
+		if track[start] != 0x80 || !bytes.Equal(track[start+1:end], first) || track[end] != byte(crc) || track[end+1] != byte(crc>>8) {
+			t.Errorf("side %d: the first block is not after the lead-in gap with its mark and CRC", side)
+		}
+		if !bytes.Equal(readTrack(track), disk.sides[side]) {
+			t.Errorf("side %d: the track reads back differently", side)
+		}
+	}
+}
+
+//TestFdsIps the changes to a disk are an IPS patch of the image, even around the offset that reads as "EOF"
+func TestFdsIps(t *testing.T) {
+	original := makeFdsImage()[16:]
+	modified := append([]byte{}, original...)
+	modified[5] ^= 0xFF
+	copy(modified[70000:], "saved game")
+	modified[len(modified)-1] = 0x42
+
+	tests := [][2][]byte{{original, modified}, {original, original}}
+	big := make([]byte, 0x460000)
+	bigModified := append([]byte{}, big...)
+	bigModified[0x454F46] = 1
+	bigModified[0x454F47] = 2
+	tests = append(tests, [2][]byte{big, bigModified})
+	for i, test := range tests {
+		patched, err := applyIps(test[0], makeIps(test[0], test[1]))
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(patched, test[1]) {
+			t.Errorf("test %d: the patched image differs", i)
+		}
+	}
+}
+
+//TestFdsCartridge the BIOS is required, the changes to the disk are saved in a .fdsdiff file and loaded back,
+//a corrupt .fdsdiff stops the loading
+func TestFdsCartridge(t *testing.T) {
+	directory := t.TempDir()
+	image := makeFdsImage()
+	path := filepath.Join(directory, "game.fds")
+	if err := ioutil.WriteFile(path, image, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCartridge(path, LoadOptions{}); err == nil {
+		t.Fatal("no error without the BIOS")
+	}
+	bios := make([]byte, 0x2000)
+	bios[0x1FFC] = 0x34
+	if err := ioutil.WriteFile(filepath.Join(directory, "disksys.rom"), bios, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCartridge(path, LoadOptions{FdsBios: path}); err == nil {
+		t.Error("no error with a BIOS of the wrong size")
+	}
+	cartridge, err := NewCartridge(path, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapper := cartridge.Mapper.(*MapperFds)
+	if mapper.Read(0xFFFC) != 0x34 {
+		t.Errorf("the BIOS is not the PRG-ROM")
+	}
+
+	// what the drive would have written
+	track := mapper.tracks[0]
+	at := bytes.Index(track, []byte("hello disk A"))
+	copy(track[at:], "HELLO DISK A")
+	mapper.written[0] = true
+	if err := cartridge.SaveBattery(); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := ioutil.ReadFile(path); !bytes.Equal(saved, image) {
+		t.Errorf("the disk image was modified")
+	}
+	if _, err := os.Stat(filepath.Join(directory, "game.fdsdiff")); err != nil {
+		t.Errorf("no .fdsdiff file: %v", err)
+	}
+
+	cartridge, err = NewCartridge(path, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reloaded := cartridge.Mapper.(*MapperFds).image()
+	if !bytes.Contains(reloaded[:fdsSideSize], []byte("HELLO DISK A")) {
+		t.Errorf("the changes to the disk are not loaded back")
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(directory, "game.fdsdiff"), []byte("PATCH\x00\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if cartridge, err := NewCartridge(path, LoadOptions{}); err == nil || cartridge != nil {
+		t.Errorf("a corrupt .fdsdiff is not an error")
+	}
+}
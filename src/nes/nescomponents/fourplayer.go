@@ -0,0 +1,93 @@
+package nescomponents
+
+//signatures of the adapters, shifted after the buttons of the two controllers of a port (read 17 to 24)
+//https://wiki.nesdev.com/w/index.php/Four_player_adapters
+const (
+	fourScoreSignature1 = 0x10 // reads as 0,0,0,1,0,0,0,0
+	fourScoreSignature2 = 0x20 // reads as 0,0,1,0,0,0,0,0
+	reportBits          = 24
+)
+
+//FourPlayerAdapter is the nes Four Score, plugged in both controller ports, or the famicom Hori 4 players adapter,
+//plugged in the expansion port. Each port shifts out 24 bits: controller 1 (or 2), controller 3 (or 4), then a signature
+type FourPlayerAdapter struct {
+	controllers [4]*Controller
+	famicom     bool    // hori adapter: the report is on D1 and the signatures are swapped
+	signatures  [2]byte // signature of each port
+	strobe      byte
+	index       [2]int // next bit of each port
+}
+
+//NewFourScore four score constructor, controllers are the ones of the players 1 to 4
+func NewFourScore(controllers [4]*Controller) *FourPlayerAdapter {
+	return &FourPlayerAdapter{
+		controllers: controllers,
+		signatures:  [2]byte{fourScoreSignature1, fourScoreSignature2},
+	}
+}
+
+//NewHoriAdapter hori 4 players adapter constructor, controllers are the ones of the players 1 to 4
+func NewHoriAdapter(controllers [4]*Controller) *FourPlayerAdapter {
+	return &FourPlayerAdapter{
+		controllers: controllers,
+		famicom:     true,
+		signatures:  [2]byte{fourScoreSignature2, fourScoreSignature1},
+	}
+}
+
+//readBit shift out the next bit of the 24-bit report of a port
+func (adapter *FourPlayerAdapter) readBit(port int) byte {
+	var value byte
+	index := adapter.index[port]
+
+	if adapter.strobe == 1 {
+		index = 0
+	}
+	switch {
+	case index < 8:
+		value = adapter.controllers[port].bit(index)
+	case index < 16:
+		value = adapter.controllers[port+2].bit(index - 8)
+	case index < reportBits:
+		value = (adapter.signatures[port] >> uint(reportBits-1-index)) & 1
+	default:
+		// once the report is over, the adapter keeps sending 1
+		value = 1
+	}
+	if adapter.strobe == 0 && index < reportBits {
+		adapter.index[port] = index + 1
+	}
+	return value
+}
+
+//Read is the expansion port side of the hori adapter: the report is on D1
+func (adapter *FourPlayerAdapter) Read(port int) byte {
+	return adapter.readBit(port) << 1
+}
+
+//Write latch the four controllers while the strobe is high
+func (adapter *FourPlayerAdapter) Write(value byte) {
+	adapter.strobe = value & 1
+	if adapter.strobe == 1 {
+		adapter.index = [2]int{}
+	}
+}
+
+//Port return the device the four score plugs in a controller port (Port1 or Port2)
+func (adapter *FourPlayerAdapter) Port(port int) InputDevice {
+	return &fourPlayerPort{adapter, port}
+}
+
+//fourPlayerPort is one of the two plugs of the four score
+type fourPlayerPort struct {
+	adapter *FourPlayerAdapter
+	port    int
+}
+
+func (plug *fourPlayerPort) Read() byte {
+	return plug.adapter.readBit(plug.port)
+}
+
+func (plug *fourPlayerPort) Write(value byte) {
+	plug.adapter.Write(value)
+}
@@ -3,6 +3,14 @@ package nescomponents
 //game loader that the cartridge will use
 const INESFileMagic = 0x1a53454e
 
+//regions of the consoles, in the timing byte of the NES 2.0 header
+const (
+	RegionNtsc  = 0
+	RegionPal   = 1
+	RegionMulti = 2 // plays on both
+	RegionDendy = 3 // famiclone of the former USSR
+)
+
 //ines format header
 type InesHeader struct {
 	// PrgRomChunks byte // number of PRG-ROM banks (16KB each)
@@ -18,6 +26,41 @@ type InesHeader struct {
 	ChrRomChunks byte    // number of CHR-ROM banks (8KB each)
 	Mapper1      byte    // control bits
 	Mapper2      byte    // control bits
-	PrgRamSize   byte    // PRG-RAM size (x 8KB)
-	_            [7]byte // unused padding
+	PrgRamSize   byte    // PRG-RAM size (x 8KB), NES 2.0: submapper in the high nibble
+	_            byte    // NES 2.0: high bits of the rom sizes (unused)
+	PrgRamShifts byte    // NES 2.0: PRG-RAM (low nibble) and battery-backed PRG-RAM (high nibble), 64 << n bytes
+	ChrRamShifts byte    // NES 2.0: CHR-RAM (low nibble) and battery-backed CHR-RAM (high nibble), 64 << n bytes
+	Timing       byte    // NES 2.0: region in the bits 0-1 (RegionNtsc, RegionPal...)
+	_            [2]byte // unused padding
+	InputDevice  byte    // NES 2.0: default expansion device
+}
+
+//IsNes2 tell whether the header uses the NES 2.0 format, whose extra bytes are meaningful
+func (header *InesHeader) IsNes2() bool {
+	return header.Mapper2&0x0C == 0x08
+}
+
+//PrgRamBytes return the size of the PRG-RAM, the volatile and the battery-backed ones together.
+//It is 8 KB when the header does not say or says less, the mappers with more bank it at $6000-$7FFF
+func (header *InesHeader) PrgRamBytes() int {
+	size := 0
+	if header.IsNes2() {
+		for _, shift := range []byte{header.PrgRamShifts & 0x0F, header.PrgRamShifts >> 4} {
+			if shift != 0 {
+				size += 64 << shift
+			}
+		}
+	}
+	if size < 0x2000 {
+		size = 0x2000
+	}
+	return size
+}
+
+//ChrRamBytes return the size of the CHR-RAM when the rom has no CHR-ROM, 8 KB when the header does not say
+func (header *InesHeader) ChrRamBytes() int {
+	if shift := header.ChrRamShifts & 0x0F; header.IsNes2() && shift != 0 {
+		return 64 << shift
+	}
+	return 0x2000
 }
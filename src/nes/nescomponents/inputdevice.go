@@ -0,0 +1,129 @@
+package nescomponents
+
+import "fmt"
+
+//InputDevice is anything plugged in one of the two controller ports: standard controller, zapper, paddle...
+type InputDevice interface {
+	// Read is a read of the port register ($4016 for port 1, $4017 for port 2),
+	// the device returns the data lines it drives (D0-D4)
+	Read() byte
+	// Write is a write to $4016, bit 0 is the strobe seen by both ports
+	Write(value byte)
+}
+
+//ExpansionDevice is plugged in the famicom expansion port, it can drive data lines of both registers
+type ExpansionDevice interface {
+	// Read is a read of $4016 (port 0) or $4017 (port 1), the device returns the data lines it drives (D0-D4)
+	Read(port int) byte
+	// Write is a write to $4016, bits 0-2 are the OUT0-OUT2 lines of the expansion port
+	Write(value byte)
+}
+
+//default input devices of the nes 2.0 header (byte 15)
+//https://wiki.nesdev.com/w/index.php/NES_2.0#Default_Expansion_Device
+const (
+	InputUnspecified     = 0x00
+	InputControllers     = 0x01
+	InputFourScore       = 0x02
+	InputFamicom4Players = 0x03
+	InputZapper          = 0x08
+	InputPowerPadA       = 0x0B
+	InputPowerPadB       = 0x0C
+	InputFamilyTrainerA  = 0x0D
+	InputFamilyTrainerB  = 0x0E
+	InputArkanoidNes     = 0x0F
+	InputArkanoidFamicom = 0x10
+	InputFamilyKeyboard  = 0x23
+)
+
+//controller ports
+const (
+	Port1 = 0
+	Port2 = 1
+)
+
+//portPlug plugs a device that drives the data lines of both registers, like an expansion device, in a controller port
+type portPlug struct {
+	device ExpansionDevice
+	port   int
+}
+
+//OnPort return a device that plugs an other one in a controller port (Port1 or Port2)
+func OnPort(device ExpansionDevice, port int) InputDevice {
+	return &portPlug{device, port}
+}
+
+func (plug *portPlug) Read() byte {
+	return plug.device.Read(plug.port)
+}
+
+func (plug *portPlug) Write(value byte) {
+	plug.device.Write(value)
+}
+
+//bits of $4016/$4017 that are not driven by the devices keep the high byte of the address (open bus)
+const inputOpenBus = 0x40
+
+//readInput is a read of $4016 (port 0) or $4017 (port 1)
+func (bus *BUS) readInput(port int) byte {
+	var data byte = inputOpenBus
+
+	if bus.ports[port] != nil {
+		data |= bus.ports[port].Read() & 0x1F
+	}
+	if bus.expansion != nil {
+		data |= bus.expansion.Read(port) & 0x1F
+	}
+	return data
+}
+
+//writeInput is a write to $4016, the strobe goes to both ports and the expansion port
+func (bus *BUS) writeInput(value byte) {
+	for _, device := range bus.ports {
+		if device != nil {
+			device.Write(value)
+		}
+	}
+	if bus.expansion != nil {
+		bus.expansion.Write(value)
+	}
+}
+
+//PlugDevice plug a device in a controller port (Port1 or Port2), nil unplugs it
+func (bus *BUS) PlugDevice(port int, device InputDevice) error {
+	if port < 0 || port >= len(bus.ports) {
+		return fmt.Errorf("no controller port %d, expected Port1 or Port2", port)
+	}
+	bus.ports[port] = device
+	return nil
+}
+
+//GetDevice return the device plugged in a controller port, nil if none or if there is no such port
+func (bus *BUS) GetDevice(port int) InputDevice {
+	if port < 0 || port >= len(bus.ports) {
+		return nil
+	}
+	return bus.ports[port]
+}
+
+//Controllers return the standard controllers of the players 1 to 4
+func (bus *BUS) Controllers() [4]*Controller {
+	return [4]*Controller{bus.Controller1, bus.Controller2, bus.Controller3, bus.Controller4}
+}
+
+//nextFrame tell the controllers a new frame starts, for their turbo and macros
+func (bus *BUS) nextFrame() {
+	for _, controller := range bus.Controllers() {
+		controller.nextFrame()
+	}
+}
+
+//PlugExpansionDevice plug a device in the famicom expansion port, nil unplugs it
+func (bus *BUS) PlugExpansionDevice(device ExpansionDevice) {
+	bus.expansion = device
+}
+
+//GetExpansionDevice return the device plugged in the famicom expansion port
+func (bus *BUS) GetExpansionDevice() ExpansionDevice {
+	return bus.expansion
+}
@@ -1,6 +1,10 @@
 package nescomponents
 
-import "fmt"
+import (
+	"fmt"
+	"log"
+	"sort"
+)
 
 type Mapper interface {
 	Read(address uint16) byte
@@ -8,24 +12,139 @@ type Mapper interface {
 	Step()
 }
 
+//PatternWatcher is a mapper that sees the pattern fetches of the ppu while it renders, with their exact address
+//(MMC2 and MMC4 switch their CHR banks when some tiles are fetched)
+type PatternWatcher interface {
+	PatternFetched(address uint16)
+}
+
+//NameTableMapper is a mapper that decides what memory the ppu sees as the nametables, $2000-$3EFF,
+//instead of the mirroring of the console RAM (the Namco 163 can map CHR-ROM there)
+type NameTableMapper interface {
+	ReadNameTable(address uint16) byte
+	WriteNameTable(address uint16, value byte)
+}
+
+//CpuStepper is a mapper with parts clocked by the cpu (irq counters counting cpu cycles, expansion audio)
+type CpuStepper interface {
+	CpuStep()
+}
+
+//AudioMapper is a mapper with expansion audio, the apu mixes its output with its own channels.
+//The output is on the scale of the apu mixer, where a 2A03 pulse at full volume is about 0.15
+type AudioMapper interface {
+	AudioOutput() float32
+}
+
+//ExpansionMapper is a mapper with registers in the expansion area of the cpu, $4020-$5FFF
+type ExpansionMapper interface {
+	ReadExpansion(address uint16) byte
+	WriteExpansion(address uint16, value byte)
+}
+
+//BatteryMapper is a mapper with battery-backed memory of its own, saved after the PRG-RAM
+type BatteryMapper interface {
+	BatteryRAM() []byte
+}
+
+//DiskMapper is a mapper with a disk drive (Famicom Disk System): the sides of the disk can be switched, and what the game
+//writes to the disk is saved as the changes to the disk image instead of the battery-backed memory
+type DiskMapper interface {
+	InsertNextSide()
+	EjectDisk()
+	DiskChanges() []byte
+	ApplyDiskChanges(changes []byte) error
+}
+
+//MapperConstructor build the mapper of a cartridge
+type MapperConstructor func(cartridge *Cartridge) Mapper
+
+//MapperInfo describe a registered mapper, the frontend lists them
+type MapperInfo struct {
+	Number     int      // iNES mapper number
+	Submappers []byte   // NES 2.0 submappers handled by the constructor, all of them when empty
+	Name       string   // chip or family, e.g. "MMC1"
+	Boards     []string // boards using it, e.g. "SNROM", "SUROM"
+	Audio      string   // expansion audio, empty if none
+}
+
+type registeredMapper struct {
+	info        MapperInfo
+	constructor MapperConstructor
+}
+
+//mappers registered by number, each mapper file registers its own in its init
+var mappers = map[int][]registeredMapper{}
+
+//RegisterMapper add a mapper to the ones NewMapper can build. The mappers outside of this package register the same way,
+//with the accessors of the Cartridge (GetPrg, GetChr, SetMirror...) and the optional interfaces above.
+//A constructor for given submappers takes precedence over the one for all the submappers of the same number
+func RegisterMapper(info MapperInfo, constructor MapperConstructor) error {
+	for _, registered := range mappers[info.Number] {
+		if overlap(registered.info.Submappers, info.Submappers) {
+			return fmt.Errorf("mapper %d (%s) already registered by %s", info.Number, info.Name, registered.info.Name)
+		}
+	}
+	mappers[info.Number] = append(mappers[info.Number], registeredMapper{info, constructor})
+	return nil
+}
+
+//registerMapper register a mapper of this package, a conflict is a mistake in the code
+func registerMapper(info MapperInfo, constructor MapperConstructor) {
+	if err := RegisterMapper(info, constructor); err != nil {
+		log.Fatal(err)
+	}
+}
+
+//overlap tell whether two lists of submappers share one, an empty list is all of them
+func overlap(submappers1 []byte, submappers2 []byte) bool {
+	if len(submappers1) == 0 || len(submappers2) == 0 {
+		return len(submappers1) == len(submappers2)
+	}
+	for _, submapper1 := range submappers1 {
+		for _, submapper2 := range submappers2 {
+			if submapper1 == submapper2 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//SupportedMappers return the registered mappers, sorted by number
+func SupportedMappers() []MapperInfo {
+	var infos []MapperInfo
+
+	for _, registered := range mappers {
+		for _, mapper := range registered {
+			infos = append(infos, mapper.info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Number != infos[j].Number {
+			return infos[i].Number < infos[j].Number
+		}
+		return len(infos[i].Submappers) > len(infos[j].Submappers)
+	})
+	return infos
+}
+
+//NewMapper build the mapper registered for the mapper and submapper of the cartridge
 func NewMapper(cartridge *Cartridge) (Mapper, error) {
-	//load appropriate mapper
-	switch cartridge.mapperType {
-	// case 0:
-	// 	return NewMapper2(cartridge), nil
-	case 1:
-		return NewMapper1(cartridge), nil
-		// case 2:
-		// 	return NewMapper2(cartridge), nil
-		// case 3:
-		// 	return NewMapper3(cartridge), nil
-		// case 4:
-		// 	return NewMapper4(console, cartridge), nil
-		// case 7:
-		// 	return NewMapper7(cartridge), nil
-		// case 225:
-		// 	return NewMapper225(cartridge), nil
+	var constructor MapperConstructor
+
+	for _, registered := range mappers[int(cartridge.mapperType)] {
+		if len(registered.info.Submappers) == 0 && constructor == nil {
+			constructor = registered.constructor
+		}
+		for _, submapper := range registered.info.Submappers {
+			if submapper == cartridge.submapper {
+				return registered.constructor(cartridge), nil
+			}
+		}
+	}
+	if constructor == nil {
+		return nil, fmt.Errorf("unsupported mapper: %d, submapper %d", cartridge.mapperType, cartridge.submapper)
 	}
-	err := fmt.Errorf("unsupported mapper: %d", cartridge.mapperType)
-	return nil, err
+	return constructor(cartridge), nil
 }
@@ -4,6 +4,13 @@ import (
 	"log"
 )
 
+//the MMC1 boards which use the CHR bank register for more than the CHR, when the CHR is 8 KB of RAM:
+//SNROM: bit 4 disables the PRG-RAM
+//SOROM: bit 3 selects the 8 KB bank of the 16 KB of PRG-RAM
+//SUROM: bit 4 selects the 256 KB half of the 512 KB of PRG-ROM
+//SXROM: bit 4 selects the PRG-ROM half like SUROM and bits 2-3 the 8 KB bank of the 32 KB of PRG-RAM
+//In the 4 KB CHR mode, the games write the same value to both registers, the first one is used
+//https://wiki.nesdev.com/w/index.php/MMC1
 type Mapper1 struct {
 	cartridge     *Cartridge
 	shiftRegister byte
@@ -15,13 +22,46 @@ type Mapper1 struct {
 	chrBank1      byte
 	prgOffsets    [2]int
 	chrOffsets    [2]int
+	ramOffset     int
+	ramDisabled   bool   // bit 4 of the PRG bank register (not on the MMC1A) or of the CHR bank register (SNROM)
+	mmc1a         bool   // the PRG-RAM is always enabled
+	snrom         bool   // bit 4 of the CHR bank disables the PRG-RAM
+	outerPrg      bool   // bit 4 of the CHR bank selects the 256 KB PRG-ROM half (SUROM, SXROM)
+	ramBankShift  byte   // position of the PRG-RAM bank in the CHR bank (SOROM: 3, SXROM: 2)
+	fixedPrg      bool   // 32 KB of PRG-ROM without bank switching (SEROM, SHROM, SH1ROM)
+	lastWrite     uint64 // cpu cycle of the last serial write
 }
 
+func init() {
+	registerMapper(MapperInfo{Number: 1, Name: "MMC1", Boards: []string{"SNROM", "SOROM", "SUROM", "SXROM", "SEROM", "SKROM", "SLROM"}}, NewMapper1)
+}
+
+//NewMapper1 choose the board from the NES 2.0 submapper (1: SUROM, 2: SOROM, 3: MMC1A, 4: SXROM, 5: SEROM),
+//else from the sizes of the PRG-ROM and PRG-RAM
 func NewMapper1(cartridge *Cartridge) Mapper {
 	mapper := Mapper1{}
 	mapper.cartridge = cartridge
 	mapper.shiftRegister = 0x10
-	mapper.prgOffsets[1] = mapper.prgBankOffset(-1)
+	mapper.prgMode = 3
+	prgSize := len(cartridge.prg)
+	ramSize := len(cartridge.sram)
+	chrRam := len(cartridge.chr) == 0x2000
+	switch {
+	case cartridge.submapper == 1 || (cartridge.submapper == 0 && prgSize > 0x40000 && ramSize < 0x8000):
+		mapper.outerPrg = true
+	case cartridge.submapper == 2 || (cartridge.submapper == 0 && ramSize == 0x4000):
+		mapper.ramBankShift = 3
+	case cartridge.submapper == 3:
+		mapper.mmc1a = true
+	case cartridge.submapper == 4 || (cartridge.submapper == 0 && ramSize >= 0x8000):
+		mapper.outerPrg = true
+		mapper.ramBankShift = 2
+	case cartridge.submapper == 5:
+		mapper.fixedPrg = true
+	default:
+		mapper.snrom = chrRam
+	}
+	mapper.updateOffsets()
 	return &mapper
 }
 
@@ -41,7 +81,10 @@ func (mapper *Mapper1) Read(address uint16) byte {
 
 		return mapper.cartridge.prg[mapper.prgOffsets[bank]+int(offset)]
 	case address >= 0x6000:
-		return mapper.cartridge.sram[int(address)-0x6000]
+		if mapper.ramDisabled {
+			return 0 // open bus
+		}
+		return mapper.cartridge.sram[mapper.ramOffset+int(address)-0x6000]
 	default:
 		log.Fatalf("unhandled mapper1 read at address: 0x%04X", address)
 	}
@@ -57,7 +100,9 @@ func (mapper *Mapper1) Write(address uint16, value byte) bool {
 	case address >= 0x8000:
 		mapper.loadRegister(address, value)
 	case address >= 0x6000:
-		mapper.cartridge.sram[int(address)-0x6000] = value
+		if !mapper.ramDisabled {
+			mapper.cartridge.sram[mapper.ramOffset+int(address)-0x6000] = value
+		}
 	default:
 		log.Fatalf("unhandled mapper1 write at address: 0x%04X", address)
 		return false
@@ -65,7 +110,16 @@ func (mapper *Mapper1) Write(address uint16, value byte) bool {
 	return true
 }
 
+//loadRegister shift a bit in the register, or reset it. The MMC1 ignores a write on the cycle after another one:
+//the read-modify-write instructions write twice in a row and only the first write counts (Bill & Ted's Excellent Adventure)
 func (mapper *Mapper1) loadRegister(address uint16, value byte) {
+	if bus := mapper.cartridge.bus; bus != nil {
+		// the cpu writes twice in the same instruction only on consecutive cycles
+		if bus.cpu.Cycles == mapper.lastWrite {
+			return
+		}
+		mapper.lastWrite = bus.cpu.Cycles
+	}
 	if value&0x80 == 0x80 {
 		mapper.shiftRegister = 0x10
 		mapper.writeControl(mapper.control | 0x0C)
@@ -124,9 +178,9 @@ func (mapper *Mapper1) writeCHRBank1(value byte) {
 	mapper.updateOffsets()
 }
 
-// PRG bank (internal, $E000-$FFFF)
+// PRG bank (internal, $E000-$FFFF), bit 4 disables the PRG-RAM
 func (mapper *Mapper1) writePRGBank(value byte) {
-	mapper.prgBank = value & 0x0F
+	mapper.prgBank = value & 0x1F
 	mapper.updateOffsets()
 }
 
@@ -158,18 +212,33 @@ func (mapper *Mapper1) chrBankOffset(index int) int {
 //                    2: fix first bank at $8000 and switch 16 KB bank at $C000;
 //                    3: fix last bank at $C000 and switch 16 KB bank at $8000)
 // CHR ROM bank mode (0: switch 8 KB at a time; 1: switch two separate 4 KB banks)
+// The first and last banks are the ones of the selected 256 KB half (SUROM, SXROM)
 func (mapper *Mapper1) updateOffsets() {
-	switch mapper.prgMode {
-	case 0, 1:
-		mapper.prgOffsets[0] = mapper.prgBankOffset(int(mapper.prgBank & 0xFE))
-		mapper.prgOffsets[1] = mapper.prgBankOffset(int(mapper.prgBank | 0x01))
-	case 2:
-		mapper.prgOffsets[0] = 0
-		mapper.prgOffsets[1] = mapper.prgBankOffset(int(mapper.prgBank))
-	case 3:
-		mapper.prgOffsets[0] = mapper.prgBankOffset(int(mapper.prgBank))
-		mapper.prgOffsets[1] = mapper.prgBankOffset(-1)
+	prgBank := int(mapper.prgBank & 0x0F)
+	outer := 0
+	if mapper.outerPrg {
+		outer = int(mapper.chrBank0 & 0x10)
+	}
+	switch {
+	case mapper.fixedPrg:
+		mapper.prgOffsets[0] = mapper.prgBankOffset(0)
+		mapper.prgOffsets[1] = mapper.prgBankOffset(1)
+	case mapper.prgMode == 0 || mapper.prgMode == 1:
+		mapper.prgOffsets[0] = mapper.prgBankOffset(outer | prgBank&0xFE)
+		mapper.prgOffsets[1] = mapper.prgBankOffset(outer | prgBank | 0x01)
+	case mapper.prgMode == 2:
+		mapper.prgOffsets[0] = mapper.prgBankOffset(outer)
+		mapper.prgOffsets[1] = mapper.prgBankOffset(outer | prgBank)
+	case mapper.prgMode == 3:
+		mapper.prgOffsets[0] = mapper.prgBankOffset(outer | prgBank)
+		mapper.prgOffsets[1] = mapper.prgBankOffset(outer | 0x0F)
+	}
+	ramBank := 0
+	if mapper.ramBankShift != 0 {
+		ramBank = int(mapper.chrBank0>>mapper.ramBankShift) & 0x03
 	}
+	mapper.ramOffset = ramBank * 0x2000 % len(mapper.cartridge.sram)
+	mapper.ramDisabled = (mapper.prgBank&0x10 != 0 && !mapper.mmc1a) || (mapper.snrom && mapper.chrBank0&0x10 != 0)
 	switch mapper.chrMode {
 	case 0:
 		mapper.chrOffsets[0] = mapper.chrBankOffset(int(mapper.chrBank0 & 0xFE))
@@ -0,0 +1,98 @@
+package nescomponents
+
+import (
+	"testing"
+)
+
+//mmc1Register return the 5 serial writes loading value in the register at address, low bit first
+func mmc1Register(address uint16, value byte) []mapperWrite {
+	var writes []mapperWrite
+
+	for bit := 0; bit < 5; bit++ {
+		writes = append(writes, mapperWrite{address, value >> bit & 1})
+	}
+	return writes
+}
+
+//mmc1Registers chain the serial writes of several registers
+func mmc1Registers(registers ...mapperWrite) []mapperWrite {
+	var writes []mapperWrite
+
+	for _, register := range registers {
+		writes = append(writes, mmc1Register(register.address, register.value)...)
+	}
+	return writes
+}
+
+var mmc1Tests = []struct {
+	name      string
+	submapper byte
+	prgBanks  int // 16 KB
+	chrBanks  int // 8 KB, 1 is the 8 KB of CHR-RAM of the boards which use the CHR bank for something else
+	ramSize   int
+	registers []mapperWrite
+	prg       [2]byte // PRG banks read at $8000 and $C000
+	ram       byte    // PRG-RAM bank read at $6000 plus $10, 0 for open bus
+}{
+	{"16 KB at $8000", 0, 16, 16, 0x2000, []mapperWrite{{0xE000, 5}}, [2]byte{5, 15}, 0x10},
+	{"16 KB at $C000", 0, 16, 16, 0x2000, []mapperWrite{{0x8000, 0x08}, {0xE000, 3}}, [2]byte{0, 3}, 0x10},
+	{"32 KB", 0, 16, 16, 0x2000, []mapperWrite{{0x8000, 0x00}, {0xE000, 5}}, [2]byte{4, 5}, 0x10},
+	{"surom low half", 0, 32, 1, 0x2000, []mapperWrite{{0xA000, 0x00}, {0xE000, 2}}, [2]byte{2, 15}, 0x10},
+	{"surom high half", 0, 32, 1, 0x2000, []mapperWrite{{0xA000, 0x10}, {0xE000, 2}}, [2]byte{18, 31}, 0x10},
+	{"surom high half 32 KB", 1, 32, 1, 0x2000, []mapperWrite{{0x8000, 0x00}, {0xA000, 0x10}, {0xE000, 2}}, [2]byte{18, 19}, 0x10},
+	{"ram disabled by the prg bank", 0, 16, 16, 0x2000, []mapperWrite{{0xE000, 0x12}}, [2]byte{2, 15}, 0},
+	{"mmc1a ram always enabled", 3, 16, 16, 0x2000, []mapperWrite{{0xE000, 0x12}}, [2]byte{2, 15}, 0x10},
+	{"snrom ram disabled by the chr bank", 0, 16, 1, 0x2000, []mapperWrite{{0xA000, 0x10}}, [2]byte{0, 15}, 0},
+	{"sorom second ram bank", 0, 16, 1, 0x4000, []mapperWrite{{0xA000, 0x08}}, [2]byte{0, 15}, 0x11},
+	{"sorom submapper", 2, 16, 1, 0x4000, []mapperWrite{{0xA000, 0x08}}, [2]byte{0, 15}, 0x11},
+	{"sxrom last ram bank and high half", 0, 32, 1, 0x8000, []mapperWrite{{0xA000, 0x1C}, {0xE000, 1}}, [2]byte{17, 31}, 0x13},
+	{"sxrom submapper first ram bank", 4, 32, 1, 0x8000, []mapperWrite{{0xA000, 0x00}, {0xE000, 1}}, [2]byte{1, 15}, 0x10},
+}
+
+func TestMmc1(t *testing.T) {
+	for _, test := range mmc1Tests {
+		cartridge := newTestCartridge(1, test.submapper, test.prgBanks, test.chrBanks)
+		cartridge.sram = make([]byte, test.ramSize)
+		for i := range cartridge.sram {
+			cartridge.sram[i] = byte(0x10 + i/0x2000)
+		}
+		mapper, err := NewMapper(cartridge)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		for _, write := range mmc1Registers(test.registers...) {
+			writeMapper(t, mapper, write)
+		}
+		prg := [2]byte{mapper.Read(0x8000), mapper.Read(0xC000)}
+		if prg != test.prg {
+			t.Errorf("%s: PRG banks %v, want %v", test.name, prg, test.prg)
+		}
+		if ram := mapper.Read(0x6000); ram != test.ram {
+			t.Errorf("%s: $6000 reads $%02X, want $%02X", test.name, ram, test.ram)
+		}
+	}
+}
+
+//TestMmc1ConsecutiveWrites the read-modify-write instructions write twice on consecutive cycles, the MMC1 only takes
+//the first write: INC $8000 shifts one bit, a second INC shifts another one
+func TestMmc1ConsecutiveWrites(t *testing.T) {
+	cartridge := newTestCartridge(1, 0, 16, 16)
+	mapper, err := NewMapper(cartridge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cartridge.Mapper = mapper
+	bus := NewBus(cartridge)
+	copy(bus.cpuRam[:], []byte{0xEE, 0x00, 0x80, 0xEE, 0x00, 0x80}) // INC $8000, INC $8000
+	bus.cpu.PC = 0
+
+	mmc1 := mapper.(*Mapper1)
+	bus.cpu.Step()
+	if mmc1.shiftRegister != 0x08 {
+		t.Errorf("shift register $%02X after an INC, want $08: one bit shifted", mmc1.shiftRegister)
+	}
+	bus.cpu.Step()
+	if mmc1.shiftRegister != 0x04 {
+		t.Errorf("shift register $%02X after two INC, want $04: two bits shifted", mmc1.shiftRegister)
+	}
+}
@@ -0,0 +1,66 @@
+package nescomponents
+
+import (
+	"log"
+)
+
+//Mapper7 is AxROM (Battletoads, Marble Madness, Wizards & Warriors): 32 KB PRG banks, 8 KB CHR-RAM
+//and a single-screen mirroring chosen by the game
+type Mapper7 struct {
+	cartridge *Cartridge
+	prgBank   int
+}
+
+func init() {
+	registerMapper(MapperInfo{Number: 7, Name: "AxROM", Boards: []string{"ANROM", "AN1ROM", "AMROM", "AOROM"}}, NewMapper7)
+}
+
+func NewMapper7(cartridge *Cartridge) Mapper {
+	mapper := Mapper7{}
+	mapper.cartridge = cartridge
+	mapper.cartridge.mirror = MirrorSingle0
+	return &mapper
+}
+
+func (mapper *Mapper7) Step() {
+}
+
+func (mapper *Mapper7) Read(address uint16) byte {
+	switch {
+	case address < 0x2000:
+		return mapper.cartridge.chr[address]
+	case address >= 0x8000:
+		index := mapper.prgBank*0x8000 + int(address-0x8000)
+		return mapper.cartridge.prg[index%len(mapper.cartridge.prg)]
+	case address >= 0x6000:
+		return mapper.cartridge.sram[int(address)-0x6000]
+	default:
+		log.Fatalf("unhandled mapper7 read at address: 0x%04X", address)
+	}
+	return 0
+}
+
+func (mapper *Mapper7) Write(address uint16, value byte) bool {
+	switch {
+	case address < 0x2000:
+		mapper.cartridge.chr[address] = value
+	case address >= 0x8000:
+		mapper.writeBank(value)
+	case address >= 0x6000:
+		mapper.cartridge.sram[int(address)-0x6000] = value
+	default:
+		log.Fatalf("unhandled mapper7 write at address: 0x%04X", address)
+		return false
+	}
+	return true
+}
+
+// Bank select ($8000-$FFFF): bits 0-2 select the 32 KB PRG bank, bit 4 the nametable (A or B) of the single screen
+func (mapper *Mapper7) writeBank(value byte) {
+	mapper.prgBank = int(value & 0x07)
+	if value&0x10 == 0 {
+		mapper.cartridge.mirror = MirrorSingle0
+	} else {
+		mapper.cartridge.mirror = MirrorSingle1
+	}
+}
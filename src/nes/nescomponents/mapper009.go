@@ -0,0 +1,150 @@
+package nescomponents
+
+import (
+	"log"
+)
+
+//Mapper9 is MMC2 (Punch-Out!!), Mapper10 the MMC4 (Fire Emblem) which only differs by its PRG banks:
+//each 4 KB half of the CHR has two banks, one for the tile $FD and one for the tile $FE,
+//a latch remembers which of the two tiles the ppu fetched last and selects the bank
+type Mapper9 struct {
+	cartridge  *Cartridge
+	mmc4       bool       // MMC4: 16 KB PRG banks, the latch 0 reacts to the whole tile
+	prgBank    byte       // 8 KB (MMC2) or 16 KB (MMC4) bank at $8000
+	chrBanks   [2][2]byte // 4 KB banks of each half, for the latch on $FD and on $FE
+	latches    [2]byte    // tile ($FD or $FE) fetched last in each half
+	prgOffsets [4]int     // 8 KB banks at $8000, $A000, $C000 and $E000
+	chrOffsets [2]int
+}
+
+func init() {
+	registerMapper(MapperInfo{Number: 9, Name: "MMC2", Boards: []string{"PNROM"}}, NewMapper9)
+	registerMapper(MapperInfo{Number: 10, Name: "MMC4", Boards: []string{"FJROM", "FKROM"}}, NewMapper10)
+}
+
+func NewMapper9(cartridge *Cartridge) Mapper {
+	mapper := Mapper9{}
+	mapper.cartridge = cartridge
+	mapper.latches = [2]byte{0xFE, 0xFE}
+	mapper.updateOffsets()
+	return &mapper
+}
+
+func NewMapper10(cartridge *Cartridge) Mapper {
+	mapper := Mapper9{mmc4: true}
+	mapper.cartridge = cartridge
+	mapper.latches = [2]byte{0xFE, 0xFE}
+	mapper.updateOffsets()
+	return &mapper
+}
+
+func (mapper *Mapper9) Step() {
+}
+
+func (mapper *Mapper9) Read(address uint16) byte {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x1000
+		offset := address % 0x1000
+		return mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)]
+	case address >= 0x8000:
+		address = address - 0x8000
+		bank := address / 0x2000
+		offset := address % 0x2000
+		return mapper.cartridge.prg[mapper.prgOffsets[bank]+int(offset)]
+	case address >= 0x6000:
+		return mapper.cartridge.sram[int(address)-0x6000]
+	default:
+		log.Fatalf("unhandled mapper9 read at address: 0x%04X", address)
+	}
+	return 0
+}
+
+func (mapper *Mapper9) Write(address uint16, value byte) bool {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x1000
+		offset := address % 0x1000
+		mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)] = value
+	case address >= 0x8000:
+		mapper.writeRegister(address, value)
+	case address >= 0x6000:
+		mapper.cartridge.sram[int(address)-0x6000] = value
+	default:
+		log.Fatalf("unhandled mapper9 write at address: 0x%04X", address)
+		return false
+	}
+	return true
+}
+
+func (mapper *Mapper9) writeRegister(address uint16, value byte) {
+	switch address & 0xF000 {
+	case 0xA000: // PRG bank
+		mapper.prgBank = value & 0x0F
+	case 0xB000: // CHR bank of $0000-$0FFF for $FD
+		mapper.chrBanks[0][0] = value & 0x1F
+	case 0xC000: // CHR bank of $0000-$0FFF for $FE
+		mapper.chrBanks[0][1] = value & 0x1F
+	case 0xD000: // CHR bank of $1000-$1FFF for $FD
+		mapper.chrBanks[1][0] = value & 0x1F
+	case 0xE000: // CHR bank of $1000-$1FFF for $FE
+		mapper.chrBanks[1][1] = value & 0x1F
+	case 0xF000:
+		if value&1 == 0 {
+			mapper.cartridge.mirror = MirrorVertical
+		} else {
+			mapper.cartridge.mirror = MirrorHorizontal
+		}
+	}
+	mapper.updateOffsets()
+}
+
+//PatternFetched move the latches: once the last byte of the tile $FD or $FE is fetched,
+//the next fetches of the same half use its bank. The MMC2 latch 0 only reacts to the address $0FD8/$0FE8
+func (mapper *Mapper9) PatternFetched(address uint16) {
+	half := address >> 12
+	tile := byte(address >> 4)
+
+	if address&0x0FF0 != 0x0FD0 && address&0x0FF0 != 0x0FE0 || address&0x08 == 0 {
+		return
+	}
+	if half == 0 && !mapper.mmc4 && address&0x0F != 0x08 {
+		return
+	}
+	mapper.latches[half] = tile
+	mapper.updateOffsets()
+}
+
+func (mapper *Mapper9) prgBankOffset(index int, size int) int {
+	index %= len(mapper.cartridge.prg) / size
+	offset := index * size
+	if offset < 0 {
+		offset += len(mapper.cartridge.prg)
+	}
+	return offset
+}
+
+func (mapper *Mapper9) chrBankOffset(index int) int {
+	index %= len(mapper.cartridge.chr) / 0x1000
+	return index * 0x1000
+}
+
+// MMC2: switchable 8 KB at $8000, the last three 8 KB banks fixed at $A000-$FFFF
+// MMC4: switchable 16 KB at $8000, the last 16 KB bank fixed at $C000
+func (mapper *Mapper9) updateOffsets() {
+	if mapper.mmc4 {
+		mapper.prgOffsets[0] = mapper.prgBankOffset(int(mapper.prgBank), 0x4000)
+		mapper.prgOffsets[1] = mapper.prgOffsets[0] + 0x2000
+		mapper.prgOffsets[2] = mapper.prgBankOffset(-1, 0x4000)
+		mapper.prgOffsets[3] = mapper.prgOffsets[2] + 0x2000
+	} else {
+		mapper.prgOffsets[0] = mapper.prgBankOffset(int(mapper.prgBank), 0x2000)
+		mapper.prgOffsets[1] = mapper.prgBankOffset(-3, 0x2000)
+		mapper.prgOffsets[2] = mapper.prgBankOffset(-2, 0x2000)
+		mapper.prgOffsets[3] = mapper.prgBankOffset(-1, 0x2000)
+	}
+	for half := range mapper.chrOffsets {
+		bank := mapper.chrBanks[half][mapper.latches[half]-0xFD]
+		mapper.chrOffsets[half] = mapper.chrBankOffset(int(bank))
+	}
+}
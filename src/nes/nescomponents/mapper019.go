@@ -0,0 +1,219 @@
+package nescomponents
+
+import (
+	"log"
+)
+
+//Mapper19 is the Namco 163 (Megami Tensei II, King of Kings): three switchable 8 KB PRG banks, eight 1 KB CHR banks
+//which can also map the nametables of the console, a 15 bit cpu cycle irq and up to 8 wavetable channels
+type Mapper19 struct {
+	cartridge     *Cartridge
+	prgBanks      [3]byte
+	chrBanks      [8]byte
+	nameTables    [4]byte // $C000-$D800: the banks of the nametables, CHR-ROM or $E0-$FF for the ones of the console
+	chrRamOff     [2]bool // the banks $E0-$FF of each half of the CHR are CHR-ROM, not the console nametables
+	prgOffsets    [4]int
+	chrOffsets    [8]int
+	chrNameTables [8]int // nametable of the console mapped by a CHR bank, -1 for CHR-ROM
+	irqCounter    uint16 // 15 bits
+	irqEnable     bool
+	irqPending    bool
+	soundOff      bool
+	audio         Namco163Audio
+}
+
+func init() {
+	registerMapper(MapperInfo{Number: 19, Name: "Namco 163", Boards: []string{"Namco 163"}, Audio: "Namco 163"}, NewMapper19)
+}
+
+func NewMapper19(cartridge *Cartridge) Mapper {
+	mapper := Mapper19{}
+	mapper.cartridge = cartridge
+	// until the game sets them, the nametables follow the mirroring of the header
+	for i, page := range MirrorLookup[cartridge.mirror] {
+		mapper.nameTables[i] = 0xE0 | byte(page&1)
+	}
+	mapper.updateOffsets()
+	return &mapper
+}
+
+func (mapper *Mapper19) Step() {
+}
+
+//CpuStep count the irq counter up to $7FFF, where it fires and stops, and clock the sound
+func (mapper *Mapper19) CpuStep() {
+	if mapper.irqEnable && mapper.irqCounter < 0x7FFF {
+		mapper.irqCounter++
+		if mapper.irqCounter == 0x7FFF {
+			mapper.irqPending = true
+		}
+	}
+	if mapper.irqPending {
+		mapper.cartridge.TriggerIRQ()
+	}
+	if !mapper.soundOff {
+		mapper.audio.step()
+	}
+}
+
+func (mapper *Mapper19) AudioOutput() float32 {
+	if mapper.soundOff {
+		return 0
+	}
+	return mapper.audio.output()
+}
+
+//BatteryRAM the internal RAM of the sound is kept by the battery with the PRG-RAM
+func (mapper *Mapper19) BatteryRAM() []byte {
+	return mapper.audio.ram[:]
+}
+
+// $4800: sound RAM data port, $5000/$5800: irq counter low and high (bit 7 of the high byte enables it)
+func (mapper *Mapper19) ReadExpansion(address uint16) byte {
+	switch address & 0xF800 {
+	case 0x4800:
+		return mapper.audio.read()
+	case 0x5000:
+		return byte(mapper.irqCounter)
+	case 0x5800:
+		value := byte(mapper.irqCounter >> 8)
+		if mapper.irqEnable {
+			value |= 0x80
+		}
+		return value
+	}
+	return 0
+}
+
+func (mapper *Mapper19) WriteExpansion(address uint16, value byte) {
+	switch address & 0xF800 {
+	case 0x4800:
+		mapper.audio.write(value)
+	case 0x5000:
+		mapper.irqCounter = mapper.irqCounter&0x7F00 | uint16(value)
+		mapper.irqPending = false
+	case 0x5800:
+		mapper.irqCounter = mapper.irqCounter&0x00FF | uint16(value&0x7F)<<8
+		mapper.irqEnable = value&0x80 != 0
+		mapper.irqPending = false
+	}
+}
+
+func (mapper *Mapper19) Read(address uint16) byte {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x0400
+		offset := address % 0x0400
+		if page := mapper.chrNameTables[bank]; page >= 0 {
+			return mapper.cartridge.bus.ppu.nameTable[page*0x0400+int(offset)]
+		}
+		return mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)]
+	case address >= 0x8000:
+		address = address - 0x8000
+		bank := address / 0x2000
+		offset := address % 0x2000
+		return mapper.cartridge.prg[mapper.prgOffsets[bank]+int(offset)]
+	case address >= 0x6000:
+		return mapper.cartridge.sram[int(address)-0x6000]
+	default:
+		log.Fatalf("unhandled mapper19 read at address: 0x%04X", address)
+	}
+	return 0
+}
+
+func (mapper *Mapper19) Write(address uint16, value byte) bool {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x0400
+		offset := address % 0x0400
+		if page := mapper.chrNameTables[bank]; page >= 0 {
+			mapper.cartridge.bus.ppu.nameTable[page*0x0400+int(offset)] = value
+		} else {
+			mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)] = value
+		}
+	case address >= 0x8000:
+		mapper.writeRegister(address, value)
+	case address >= 0x6000:
+		mapper.cartridge.sram[int(address)-0x6000] = value
+	default:
+		log.Fatalf("unhandled mapper19 write at address: 0x%04X", address)
+		return false
+	}
+	return true
+}
+
+func (mapper *Mapper19) writeRegister(address uint16, value byte) {
+	switch index := int(address-0x8000) / 0x0800; {
+	case index < 8: // $8000-$B800: CHR banks
+		mapper.chrBanks[index] = value
+	case index < 12: // $C000-$D800: nametables
+		mapper.nameTables[index-8] = value
+	case index == 12: // $E000: PRG bank at $8000, bit 6 turns the sound off
+		mapper.prgBanks[0] = value & 0x3F
+		mapper.soundOff = value&0x40 != 0
+	case index == 13: // $E800: PRG bank at $A000, bits 6 and 7 keep the banks $E0-$FF on the CHR-ROM
+		mapper.prgBanks[1] = value & 0x3F
+		mapper.chrRamOff[0] = value&0x40 != 0
+		mapper.chrRamOff[1] = value&0x80 != 0
+	case index == 14: // $F000: PRG bank at $C000
+		mapper.prgBanks[2] = value & 0x3F
+	case index == 15: // $F800: sound RAM address
+		mapper.audio.writeAddress(value)
+	}
+	mapper.updateOffsets()
+}
+
+//nameTableMemory return the memory and the offset a nametable address is mapped to: banks $E0-$FF select a nametable
+//of the console (bit 0), the others a 1 KB bank of CHR-ROM, whatever $E800 says
+func (mapper *Mapper19) nameTableMemory(address uint16) ([]byte, int) {
+	address = (address - 0x2000) % 0x1000
+	bank := mapper.nameTables[address/0x0400]
+	offset := int(address % 0x0400)
+	if bank >= 0xE0 {
+		return mapper.cartridge.bus.ppu.nameTable[:], int(bank&1)*0x0400 + offset
+	}
+	return mapper.cartridge.chr, mapper.chrBankOffset(int(bank)) + offset
+}
+
+func (mapper *Mapper19) ReadNameTable(address uint16) byte {
+	memory, offset := mapper.nameTableMemory(address)
+	return memory[offset]
+}
+
+//WriteNameTable only the nametables of the console can be written, not the CHR-ROM
+func (mapper *Mapper19) WriteNameTable(address uint16, value byte) {
+	if mapper.nameTables[(address-0x2000)%0x1000/0x0400] >= 0xE0 {
+		memory, offset := mapper.nameTableMemory(address)
+		memory[offset] = value
+	}
+}
+
+func (mapper *Mapper19) prgBankOffset(index int) int {
+	index %= len(mapper.cartridge.prg) / 0x2000
+	offset := index * 0x2000
+	if offset < 0 {
+		offset += len(mapper.cartridge.prg)
+	}
+	return offset
+}
+
+func (mapper *Mapper19) chrBankOffset(index int) int {
+	index %= len(mapper.cartridge.chr) / 0x0400
+	return index * 0x0400
+}
+
+// $8000, $A000 and $C000 switchable, $E000 last 8 KB bank
+// a CHR bank $E0-$FF maps a nametable of the console, unless $E800 says otherwise for its half
+func (mapper *Mapper19) updateOffsets() {
+	for i, bank := range mapper.prgBanks {
+		mapper.prgOffsets[i] = mapper.prgBankOffset(int(bank))
+	}
+	mapper.prgOffsets[3] = mapper.prgBankOffset(-1)
+	for i, bank := range mapper.chrBanks {
+		mapper.chrOffsets[i] = mapper.chrBankOffset(int(bank))
+		mapper.chrNameTables[i] = -1
+		if bank >= 0xE0 && !mapper.chrRamOff[i/4] && mapper.cartridge.bus != nil {
+			mapper.chrNameTables[i] = int(bank & 1)
+		}
+	}
+}
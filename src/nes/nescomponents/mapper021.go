@@ -0,0 +1,298 @@
+package nescomponents
+
+import (
+	"log"
+)
+
+//Mapper21 is the Konami VRC2/VRC4 family (mappers 21, 22, 23 and 25): two switchable 8 KB PRG banks,
+//eight 1 KB CHR banks and, on the VRC4, an irq counter. The boards wire two cpu address lines of their choice
+//to the register select pins, the NES 2.0 submapper tells which ones
+type Mapper21 struct {
+	cartridge  *Cartridge
+	line0      uint16 // address lines wired to the register select pin 0, several when the board is unknown
+	line1      uint16 // address lines wired to the register select pin 1
+	vrc2       bool   // no prg swap mode, 1 bit mirroring and no irq
+	chrShift   byte   // VRC2a ignores the low bit of the CHR banks
+	prgBanks   [2]byte
+	prgMode    byte // 0: switchable banks at $8000 and $A000, 1: at $C000 and $A000
+	chrBanks   [8]uint16
+	prgOffsets [4]int
+	chrOffsets [8]int
+	irq        vrcIrq
+}
+
+//vrcIrq is the irq counter of the Konami VRC4, VRC6 and VRC7: an 8 bit counter clocked every scanline
+//(a prescaler of the cpu cycles) or every cpu cycle, which fires and reloads its latch when it overflows
+type vrcIrq struct {
+	latch     byte
+	control   byte // bit 0: enable after acknowledge, bit 1: enable, bit 2: cpu cycle mode
+	counter   byte
+	prescaler int // cpu cycles x3 left before the next scanline, in scanline mode
+	pending   bool
+}
+
+//address lines of the register select pins of the VRC boards
+const (
+	vrcA0 = 1 << iota
+	vrcA1
+	vrcA2
+	vrcA3
+	_
+	_
+	vrcA6
+	vrcA7
+)
+
+func init() {
+	registerMapper(MapperInfo{Number: 21, Name: "VRC4", Boards: []string{"VRC4a", "VRC4c"}}, NewMapper21)
+	registerMapper(MapperInfo{Number: 22, Name: "VRC2", Boards: []string{"VRC2a"}}, NewMapper22)
+	registerMapper(MapperInfo{Number: 23, Name: "VRC2/VRC4", Boards: []string{"VRC2b", "VRC4e", "VRC4f"}}, NewMapper23)
+	registerMapper(MapperInfo{Number: 25, Name: "VRC2/VRC4", Boards: []string{"VRC2c", "VRC4b", "VRC4d"}}, NewMapper25)
+}
+
+func newMapperVrc(cartridge *Cartridge, line0, line1 uint16, vrc2 bool) *Mapper21 {
+	mapper := Mapper21{}
+	mapper.cartridge = cartridge
+	mapper.line0 = line0
+	mapper.line1 = line1
+	mapper.vrc2 = vrc2
+	mapper.updateOffsets()
+	return &mapper
+}
+
+//NewMapper21 VRC4a (submapper 1, A1 A2) and VRC4c (submapper 2, A6 A7)
+func NewMapper21(cartridge *Cartridge) Mapper {
+	switch cartridge.submapper {
+	case 1:
+		return newMapperVrc(cartridge, vrcA1, vrcA2, false)
+	case 2:
+		return newMapperVrc(cartridge, vrcA6, vrcA7, false)
+	}
+	return newMapperVrc(cartridge, vrcA1|vrcA6, vrcA2|vrcA7, false)
+}
+
+//NewMapper22 VRC2a (A1 A0)
+func NewMapper22(cartridge *Cartridge) Mapper {
+	mapper := newMapperVrc(cartridge, vrcA1, vrcA0, true)
+	mapper.chrShift = 1
+	return mapper
+}
+
+//NewMapper23 VRC4f (submapper 1, A0 A1), VRC4e (submapper 2, A2 A3) and VRC2b (submapper 3, A0 A1).
+//Without submapper it is a VRC4 answering on both wirings, which also runs the VRC2b games
+func NewMapper23(cartridge *Cartridge) Mapper {
+	switch cartridge.submapper {
+	case 1:
+		return newMapperVrc(cartridge, vrcA0, vrcA1, false)
+	case 2:
+		return newMapperVrc(cartridge, vrcA2, vrcA3, false)
+	case 3:
+		return newMapperVrc(cartridge, vrcA0, vrcA1, true)
+	}
+	return newMapperVrc(cartridge, vrcA0|vrcA2, vrcA1|vrcA3, false)
+}
+
+//NewMapper25 VRC4b (submapper 1, A1 A0), VRC4d (submapper 2, A3 A2) and VRC2c (submapper 3, A1 A0).
+//Without submapper it is a VRC4 answering on both wirings, which also runs the VRC2c games
+func NewMapper25(cartridge *Cartridge) Mapper {
+	switch cartridge.submapper {
+	case 1:
+		return newMapperVrc(cartridge, vrcA1, vrcA0, false)
+	case 2:
+		return newMapperVrc(cartridge, vrcA3, vrcA2, false)
+	case 3:
+		return newMapperVrc(cartridge, vrcA1, vrcA0, true)
+	}
+	return newMapperVrc(cartridge, vrcA1|vrcA3, vrcA0|vrcA2, false)
+}
+
+func (mapper *Mapper21) Step() {
+}
+
+func (mapper *Mapper21) CpuStep() {
+	mapper.irq.step(mapper.cartridge)
+}
+
+//step clock the counter, every cpu cycle or every scanline (341 ppu cycles) with the prescaler,
+//the irq stays asserted until it is acknowledged
+func (irq *vrcIrq) step(cartridge *Cartridge) {
+	if irq.control&0x02 != 0 {
+		if irq.control&0x04 != 0 {
+			irq.clock()
+		} else {
+			irq.prescaler -= 3
+			if irq.prescaler <= 0 {
+				irq.prescaler += 341
+				irq.clock()
+			}
+		}
+	}
+	if irq.pending {
+		cartridge.TriggerIRQ()
+	}
+}
+
+func (irq *vrcIrq) clock() {
+	if irq.counter == 0xFF {
+		irq.counter = irq.latch
+		irq.pending = true
+	} else {
+		irq.counter++
+	}
+}
+
+//writeControl set the mode, enabling the counter reloads it from the latch
+func (irq *vrcIrq) writeControl(value byte) {
+	irq.control = value & 0x07
+	if value&0x02 != 0 {
+		irq.counter = irq.latch
+		irq.prescaler = 341
+	}
+	irq.pending = false
+}
+
+//acknowledge clear the irq, the enable after acknowledge bit becomes the enable bit
+func (irq *vrcIrq) acknowledge() {
+	irq.control = irq.control&0x05 | (irq.control&1)<<1
+	irq.pending = false
+}
+
+func (mapper *Mapper21) Read(address uint16) byte {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x0400
+		offset := address % 0x0400
+		return mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)]
+	case address >= 0x8000:
+		address = address - 0x8000
+		bank := address / 0x2000
+		offset := address % 0x2000
+		return mapper.cartridge.prg[mapper.prgOffsets[bank]+int(offset)]
+	case address >= 0x6000:
+		return mapper.cartridge.sram[int(address)-0x6000]
+	default:
+		log.Fatalf("unhandled mapper21 read at address: 0x%04X", address)
+	}
+	return 0
+}
+
+func (mapper *Mapper21) Write(address uint16, value byte) bool {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x0400
+		offset := address % 0x0400
+		mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)] = value
+	case address >= 0x8000:
+		mapper.writeRegister(address, value)
+	case address >= 0x6000:
+		mapper.cartridge.sram[int(address)-0x6000] = value
+	default:
+		log.Fatalf("unhandled mapper21 write at address: 0x%04X", address)
+		return false
+	}
+	return true
+}
+
+//register return which of the 4 registers of a $x000 range the address selects, through the board wiring
+func (mapper *Mapper21) register(address uint16) int {
+	var register int
+
+	if address&mapper.line0 != 0 {
+		register |= 1
+	}
+	if address&mapper.line1 != 0 {
+		register |= 2
+	}
+	return register
+}
+
+func (mapper *Mapper21) writeRegister(address uint16, value byte) {
+	register := mapper.register(address)
+
+	switch address & 0xF000 {
+	case 0x8000: // PRG bank at $8000 (or $C000)
+		mapper.prgBanks[0] = value & 0x1F
+	case 0x9000:
+		if register < 2 || mapper.vrc2 {
+			mapper.writeMirroring(value)
+		} else {
+			mapper.prgMode = (value >> 1) & 1
+		}
+	case 0xA000: // PRG bank at $A000
+		mapper.prgBanks[1] = value & 0x1F
+	case 0xB000, 0xC000, 0xD000, 0xE000: // CHR banks, low nibble then high bits
+		index := int((address>>12)-0xB)*2 + register>>1
+		if register&1 == 0 {
+			mapper.chrBanks[index] = mapper.chrBanks[index]&0x1F0 | uint16(value&0x0F)
+		} else {
+			mapper.chrBanks[index] = mapper.chrBanks[index]&0x0F | uint16(value&0x1F)<<4
+		}
+	case 0xF000:
+		if !mapper.vrc2 {
+			mapper.writeIrq(register, value)
+		}
+	}
+	mapper.updateOffsets()
+}
+
+// Mirroring ($9000): 0 vertical, 1 horizontal, 2 and 3 single screen (VRC4 only)
+func (mapper *Mapper21) writeMirroring(value byte) {
+	if mapper.vrc2 {
+		value &= 1
+	}
+	switch value & 3 {
+	case 0:
+		mapper.cartridge.mirror = MirrorVertical
+	case 1:
+		mapper.cartridge.mirror = MirrorHorizontal
+	case 2:
+		mapper.cartridge.mirror = MirrorSingle0
+	case 3:
+		mapper.cartridge.mirror = MirrorSingle1
+	}
+}
+
+// IRQ ($F000-$F003): latch low nibble, latch high nibble, control and acknowledge
+func (mapper *Mapper21) writeIrq(register int, value byte) {
+	switch register {
+	case 0:
+		mapper.irq.latch = mapper.irq.latch&0xF0 | value&0x0F
+	case 1:
+		mapper.irq.latch = mapper.irq.latch&0x0F | value<<4
+	case 2:
+		mapper.irq.writeControl(value)
+	case 3:
+		mapper.irq.acknowledge()
+	}
+}
+
+func (mapper *Mapper21) prgBankOffset(index int) int {
+	index %= len(mapper.cartridge.prg) / 0x2000
+	offset := index * 0x2000
+	if offset < 0 {
+		offset += len(mapper.cartridge.prg)
+	}
+	return offset
+}
+
+func (mapper *Mapper21) chrBankOffset(index int) int {
+	index %= len(mapper.cartridge.chr) / 0x0400
+	return index * 0x0400
+}
+
+// PRG mode 0: $8000 switchable, $A000 switchable, $C000 second last bank, $E000 last bank
+// PRG mode 1: $8000 second last bank, $A000 switchable, $C000 switchable, $E000 last bank
+func (mapper *Mapper21) updateOffsets() {
+	if mapper.prgMode == 0 {
+		mapper.prgOffsets[0] = mapper.prgBankOffset(int(mapper.prgBanks[0]))
+		mapper.prgOffsets[2] = mapper.prgBankOffset(-2)
+	} else {
+		mapper.prgOffsets[0] = mapper.prgBankOffset(-2)
+		mapper.prgOffsets[2] = mapper.prgBankOffset(int(mapper.prgBanks[0]))
+	}
+	mapper.prgOffsets[1] = mapper.prgBankOffset(int(mapper.prgBanks[1]))
+	mapper.prgOffsets[3] = mapper.prgBankOffset(-1)
+	for i, bank := range mapper.chrBanks {
+		mapper.chrOffsets[i] = mapper.chrBankOffset(int(bank >> mapper.chrShift))
+	}
+}
@@ -0,0 +1,56 @@
+package nescomponents
+
+import (
+	"testing"
+)
+
+//TestVrc4WithoutSubmapper an iNES 1.0 rom of mapper 23 or 25 is a VRC4 answering on both wirings: its irq
+//counts, whichever pair of address lines the game writes the registers through
+func TestVrc4WithoutSubmapper(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapper  byte
+		latch   [2]uint16 // latch low and high nibble
+		control uint16
+	}{
+		{"23 through A0 A1 (VRC4f)", 23, [2]uint16{0xF000, 0xF001}, 0xF002},
+		{"23 through A2 A3 (VRC4e, Gradius II)", 23, [2]uint16{0xF000, 0xF004}, 0xF008},
+		{"25 through A1 A0 (VRC4b)", 25, [2]uint16{0xF000, 0xF002}, 0xF001},
+		{"25 through A3 A2 (VRC4d)", 25, [2]uint16{0xF000, 0xF008}, 0xF004},
+	}
+
+	for _, test := range tests {
+		cartridge, err := NewCartridge(writeRom(t, makeRom(test.mapper, 16, 16)), LoadOptions{})
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		mapper := cartridge.Mapper.(*Mapper21)
+		if mapper.vrc2 {
+			t.Errorf("%s: taken for a VRC2, the irq is lost", test.name)
+			continue
+		}
+		mapper.Write(test.latch[0], 0x0E)
+		mapper.Write(test.latch[1], 0x0F)
+		mapper.Write(test.control, 0x06) // enabled, counting cpu cycles
+		mapper.CpuStep()
+		if mapper.irq.pending {
+			t.Errorf("%s: irq after 1 cycle from $FE", test.name)
+		}
+		mapper.CpuStep()
+		if !mapper.irq.pending {
+			t.Errorf("%s: no irq after 2 cycles from $FE", test.name)
+		}
+	}
+}
+
+//TestVrc2Submapper the submapper 3 is a VRC2, without irq
+func TestVrc2Submapper(t *testing.T) {
+	mapper, err := NewMapper(newTestCartridge(23, 3, 16, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mapper.Write(0xF002, 0x06)
+	if vrc := mapper.(*Mapper21); !vrc.vrc2 || vrc.irq.control != 0 {
+		t.Errorf("the VRC2 has an irq: vrc2 %v, control %d", vrc.vrc2, vrc.irq.control)
+	}
+}
@@ -0,0 +1,274 @@
+package nescomponents
+
+import (
+	"log"
+)
+
+//level of one step of the VRC6 channels, the same as a step of a 2A03 pulse
+const vrc6OutputStep = 0.00752
+
+//Mapper24 is the Konami VRC6 (Akumajou Densetsu), mapper 26 is the same chip with A0 and A1 swapped (Madara, Esper Dream 2):
+//a 16 KB and an 8 KB PRG bank, eight 1 KB CHR banks, the VRC irq and two pulses and a saw of expansion audio
+type Mapper24 struct {
+	cartridge  *Cartridge
+	swapped    bool // mapper 26: the A0 and A1 lines are swapped
+	prgBanks   [2]byte
+	chrBanks   [8]byte
+	prgOffsets [4]int
+	chrOffsets [8]int
+	irq        vrcIrq
+	pulses     [2]vrc6Pulse
+	saw        vrc6Saw
+	halt       bool // frequency control: all the channels are stopped
+}
+
+func init() {
+	registerMapper(MapperInfo{Number: 24, Name: "VRC6", Boards: []string{"VRC6a"}, Audio: "VRC6"}, NewMapper24)
+	registerMapper(MapperInfo{Number: 26, Name: "VRC6", Boards: []string{"VRC6b"}, Audio: "VRC6"}, NewMapper26)
+}
+
+func NewMapper24(cartridge *Cartridge) Mapper {
+	mapper := Mapper24{}
+	mapper.cartridge = cartridge
+	mapper.updateOffsets()
+	return &mapper
+}
+
+func NewMapper26(cartridge *Cartridge) Mapper {
+	mapper := Mapper24{swapped: true}
+	mapper.cartridge = cartridge
+	mapper.updateOffsets()
+	return &mapper
+}
+
+func (mapper *Mapper24) Step() {
+}
+
+//CpuStep clock the irq counter and the audio channels
+func (mapper *Mapper24) CpuStep() {
+	mapper.irq.step(mapper.cartridge)
+	if !mapper.halt {
+		mapper.pulses[0].step()
+		mapper.pulses[1].step()
+		mapper.saw.step()
+	}
+}
+
+//AudioOutput mix the three channels, linearly
+func (mapper *Mapper24) AudioOutput() float32 {
+	sum := mapper.pulses[0].output() + mapper.pulses[1].output() + mapper.saw.output()
+	return vrc6OutputStep * float32(sum)
+}
+
+func (mapper *Mapper24) Read(address uint16) byte {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x0400
+		offset := address % 0x0400
+		return mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)]
+	case address >= 0x8000:
+		address = address - 0x8000
+		bank := address / 0x2000
+		offset := address % 0x2000
+		return mapper.cartridge.prg[mapper.prgOffsets[bank]+int(offset)]
+	case address >= 0x6000:
+		return mapper.cartridge.sram[int(address)-0x6000]
+	default:
+		log.Fatalf("unhandled mapper24 read at address: 0x%04X", address)
+	}
+	return 0
+}
+
+func (mapper *Mapper24) Write(address uint16, value byte) bool {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x0400
+		offset := address % 0x0400
+		mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)] = value
+	case address >= 0x8000:
+		mapper.writeRegister(address, value)
+	case address >= 0x6000:
+		mapper.cartridge.sram[int(address)-0x6000] = value
+	default:
+		log.Fatalf("unhandled mapper24 write at address: 0x%04X", address)
+		return false
+	}
+	return true
+}
+
+func (mapper *Mapper24) writeRegister(address uint16, value byte) {
+	register := int(address & 3)
+
+	if mapper.swapped {
+		register = register>>1 | (register&1)<<1
+	}
+	switch address & 0xF000 {
+	case 0x8000: // 16 KB PRG bank at $8000
+		mapper.prgBanks[0] = value & 0x0F
+	case 0x9000:
+		if register == 3 {
+			mapper.halt = value&1 != 0
+		} else {
+			mapper.pulses[0].write(register, value)
+		}
+	case 0xA000:
+		if register < 3 {
+			mapper.pulses[1].write(register, value)
+		}
+	case 0xB000:
+		if register == 3 {
+			mapper.writeBanking(value)
+		} else {
+			mapper.saw.write(register, value)
+		}
+	case 0xC000: // 8 KB PRG bank at $C000
+		mapper.prgBanks[1] = value & 0x1F
+	case 0xD000, 0xE000: // 1 KB CHR banks
+		mapper.chrBanks[int((address>>12)-0xD)*4+register] = value
+	case 0xF000:
+		switch register {
+		case 0:
+			mapper.irq.latch = value
+		case 1:
+			mapper.irq.writeControl(value)
+		case 2:
+			mapper.irq.acknowledge()
+		}
+	}
+	mapper.updateOffsets()
+}
+
+// PPU banking ($B003): bits 2-3 are the mirroring, the CHR banks are used in the 1 KB mode of all the games
+func (mapper *Mapper24) writeBanking(value byte) {
+	switch (value >> 2) & 3 {
+	case 0:
+		mapper.cartridge.mirror = MirrorVertical
+	case 1:
+		mapper.cartridge.mirror = MirrorHorizontal
+	case 2:
+		mapper.cartridge.mirror = MirrorSingle0
+	case 3:
+		mapper.cartridge.mirror = MirrorSingle1
+	}
+}
+
+func (mapper *Mapper24) prgBankOffset(index int, size int) int {
+	index %= len(mapper.cartridge.prg) / size
+	offset := index * size
+	if offset < 0 {
+		offset += len(mapper.cartridge.prg)
+	}
+	return offset
+}
+
+func (mapper *Mapper24) chrBankOffset(index int) int {
+	index %= len(mapper.cartridge.chr) / 0x0400
+	return index * 0x0400
+}
+
+// $8000 16 KB switchable, $C000 8 KB switchable, $E000 last 8 KB bank
+func (mapper *Mapper24) updateOffsets() {
+	mapper.prgOffsets[0] = mapper.prgBankOffset(int(mapper.prgBanks[0]), 0x4000)
+	mapper.prgOffsets[1] = mapper.prgOffsets[0] + 0x2000
+	mapper.prgOffsets[2] = mapper.prgBankOffset(int(mapper.prgBanks[1]), 0x2000)
+	mapper.prgOffsets[3] = mapper.prgBankOffset(-1, 0x2000)
+	for i, bank := range mapper.chrBanks {
+		mapper.chrOffsets[i] = mapper.chrBankOffset(int(bank))
+	}
+}
+
+//vrc6Pulse is a pulse channel of the VRC6: 16 steps, the duty says how many are high
+type vrc6Pulse struct {
+	enabled  bool
+	mode     bool // the output is always high, the duty is ignored
+	duty     byte
+	volume   byte
+	period   uint16
+	timer    uint16
+	position byte
+}
+
+// $9000/$A000: mode, duty and volume, $9001/$A001: period low, $9002/$A002: enable and period high
+func (p *vrc6Pulse) write(register int, value byte) {
+	switch register {
+	case 0:
+		p.mode = value&0x80 != 0
+		p.duty = (value >> 4) & 7
+		p.volume = value & 0x0F
+	case 1:
+		p.period = p.period&0x0F00 | uint16(value)
+	case 2:
+		p.period = p.period&0x00FF | uint16(value&0x0F)<<8
+		p.enabled = value&0x80 != 0
+		if !p.enabled {
+			p.position = 15
+		}
+	}
+}
+
+func (p *vrc6Pulse) step() {
+	if !p.enabled {
+		return
+	}
+	if p.timer == 0 {
+		p.timer = p.period
+		p.position = (p.position + 15) % 16
+	} else {
+		p.timer--
+	}
+}
+
+func (p *vrc6Pulse) output() byte {
+	if !p.enabled || (!p.mode && p.position > p.duty) {
+		return 0
+	}
+	return p.volume
+}
+
+//vrc6Saw is the saw channel of the VRC6: an accumulator increased every other step and cleared after 14 steps
+type vrc6Saw struct {
+	enabled     bool
+	rate        byte
+	period      uint16
+	timer       uint16
+	position    byte
+	accumulator byte
+}
+
+// $B000: accumulator rate, $B001: period low, $B002: enable and period high
+func (s *vrc6Saw) write(register int, value byte) {
+	switch register {
+	case 0:
+		s.rate = value & 0x3F
+	case 1:
+		s.period = s.period&0x0F00 | uint16(value)
+	case 2:
+		s.period = s.period&0x00FF | uint16(value&0x0F)<<8
+		s.enabled = value&0x80 != 0
+		if !s.enabled {
+			s.position, s.accumulator = 0, 0
+		}
+	}
+}
+
+func (s *vrc6Saw) step() {
+	if !s.enabled {
+		return
+	}
+	if s.timer > 0 {
+		s.timer--
+		return
+	}
+	s.timer = s.period
+	s.position++
+	if s.position == 14 {
+		s.position, s.accumulator = 0, 0
+	} else if s.position%2 == 0 {
+		s.accumulator += s.rate
+	}
+}
+
+//output is the 5 high bits of the accumulator
+func (s *vrc6Saw) output() byte {
+	return s.accumulator >> 3
+}
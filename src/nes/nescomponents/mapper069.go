@@ -0,0 +1,177 @@
+package nescomponents
+
+import (
+	"log"
+)
+
+//Mapper69 is the Sunsoft FME-7 (Batman: Return of the Joker) and the 5B (Gimmick!), the FME-7 with a sound chip:
+//a command register selects which bank, mirroring or irq register the parameter register writes
+type Mapper69 struct {
+	cartridge  *Cartridge
+	command    byte
+	prgBanks   [4]byte // $6000, $8000, $A000 and $C000
+	chrBanks   [8]byte
+	prgOffsets [4]int
+	chrOffsets [8]int
+	irqEnable  bool
+	irqCount   bool // the counter counts down
+	irqCounter uint16
+	irqPending bool
+	audio      Sunsoft5BAudio
+}
+
+func init() {
+	registerMapper(MapperInfo{Number: 69, Name: "Sunsoft FME-7", Boards: []string{"JLROM", "JSROM", "Sunsoft 5B"}, Audio: "Sunsoft 5B"}, NewMapper69)
+}
+
+func NewMapper69(cartridge *Cartridge) Mapper {
+	mapper := Mapper69{}
+	mapper.cartridge = cartridge
+	mapper.audio.reset()
+	mapper.updateOffsets()
+	return &mapper
+}
+
+func (mapper *Mapper69) Step() {
+}
+
+//CpuStep count down the irq counter, which fires when it wraps from 0 to $FFFF, and clock the sound
+func (mapper *Mapper69) CpuStep() {
+	if mapper.irqCount {
+		mapper.irqCounter--
+		if mapper.irqCounter == 0xFFFF && mapper.irqEnable {
+			mapper.irqPending = true
+		}
+	}
+	if mapper.irqPending {
+		mapper.cartridge.TriggerIRQ()
+	}
+	mapper.audio.step()
+}
+
+func (mapper *Mapper69) AudioOutput() float32 {
+	return mapper.audio.output()
+}
+
+func (mapper *Mapper69) Read(address uint16) byte {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x0400
+		offset := address % 0x0400
+		return mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)]
+	case address >= 0x8000:
+		address = address - 0x8000
+		bank := address / 0x2000
+		offset := address % 0x2000
+		return mapper.cartridge.prg[mapper.prgOffsets[bank]+int(offset)]
+	case address >= 0x6000:
+		return mapper.readLowBank(address)
+	default:
+		log.Fatalf("unhandled mapper69 read at address: 0x%04X", address)
+	}
+	return 0
+}
+
+func (mapper *Mapper69) Write(address uint16, value byte) bool {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x0400
+		offset := address % 0x0400
+		mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)] = value
+	case address >= 0x8000:
+		mapper.writeRegister(address, value)
+	case address >= 0x6000:
+		// only the PRG-RAM, when it is enabled and mapped
+		if mapper.prgBanks[0]&0xC0 == 0xC0 {
+			mapper.cartridge.sram[int(address)-0x6000] = value
+		}
+	default:
+		log.Fatalf("unhandled mapper69 write at address: 0x%04X", address)
+		return false
+	}
+	return true
+}
+
+// $6000-$7FFF: bit 6 of the bank maps the PRG-RAM instead of a PRG-ROM bank, bit 7 enables the RAM
+func (mapper *Mapper69) readLowBank(address uint16) byte {
+	bank := mapper.prgBanks[0]
+
+	if bank&0x40 == 0 {
+		index := int(bank&0x3F)*0x2000 + int(address-0x6000)
+		return mapper.cartridge.prg[index%len(mapper.cartridge.prg)]
+	}
+	if bank&0x80 == 0 {
+		return 0 // open bus
+	}
+	return mapper.cartridge.sram[int(address)-0x6000]
+}
+
+// $8000: command, $A000: parameter, $C000: sound register select, $E000: sound register write
+func (mapper *Mapper69) writeRegister(address uint16, value byte) {
+	switch address & 0xE000 {
+	case 0x8000:
+		mapper.command = value & 0x0F
+	case 0xA000:
+		mapper.writeParameter(value)
+	case 0xC000:
+		mapper.audio.address = value
+	case 0xE000:
+		mapper.audio.writeRegister(value)
+	}
+}
+
+func (mapper *Mapper69) writeParameter(value byte) {
+	switch command := mapper.command; {
+	case command < 8: // 1 KB CHR banks
+		mapper.chrBanks[command] = value
+	case command == 8: // $6000 bank
+		mapper.prgBanks[0] = value
+	case command < 0x0C: // 8 KB PRG banks at $8000, $A000 and $C000
+		mapper.prgBanks[command-8] = value & 0x3F
+	case command == 0x0C:
+		switch value & 3 {
+		case 0:
+			mapper.cartridge.mirror = MirrorVertical
+		case 1:
+			mapper.cartridge.mirror = MirrorHorizontal
+		case 2:
+			mapper.cartridge.mirror = MirrorSingle0
+		case 3:
+			mapper.cartridge.mirror = MirrorSingle1
+		}
+	case command == 0x0D: // irq control, acknowledges the irq
+		mapper.irqEnable = value&0x01 != 0
+		mapper.irqCount = value&0x80 != 0
+		mapper.irqPending = false
+	case command == 0x0E:
+		mapper.irqCounter = mapper.irqCounter&0xFF00 | uint16(value)
+	case command == 0x0F:
+		mapper.irqCounter = mapper.irqCounter&0x00FF | uint16(value)<<8
+	}
+	mapper.updateOffsets()
+}
+
+func (mapper *Mapper69) prgBankOffset(index int) int {
+	index %= len(mapper.cartridge.prg) / 0x2000
+	offset := index * 0x2000
+	if offset < 0 {
+		offset += len(mapper.cartridge.prg)
+	}
+	return offset
+}
+
+func (mapper *Mapper69) chrBankOffset(index int) int {
+	index %= len(mapper.cartridge.chr) / 0x0400
+	return index * 0x0400
+}
+
+// $8000, $A000 and $C000 switchable, $E000 last 8 KB bank
+func (mapper *Mapper69) updateOffsets() {
+	for i := 0; i < 3; i++ {
+		mapper.prgOffsets[i] = mapper.prgBankOffset(int(mapper.prgBanks[i+1]))
+	}
+	mapper.prgOffsets[3] = mapper.prgBankOffset(-1)
+	for i, bank := range mapper.chrBanks {
+		mapper.chrOffsets[i] = mapper.chrBankOffset(int(bank))
+	}
+}
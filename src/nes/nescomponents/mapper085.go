@@ -0,0 +1,178 @@
+package nescomponents
+
+import (
+	"log"
+)
+
+//Mapper85 is the Konami VRC7 (Lagrange Point, Tiny Toon Adventures 2): three switchable 8 KB PRG banks,
+//eight 1 KB CHR banks, the VRC irq and a FM synthesizer. The second register of each range is selected
+//by A4 on the VRC7a (submapper 2) and by A3 on the VRC7b (submapper 1)
+type Mapper85 struct {
+	cartridge  *Cartridge
+	line       uint16 // address lines selecting the second register, both when the board is unknown
+	prgBanks   [3]byte
+	chrBanks   [8]byte
+	prgOffsets [4]int
+	chrOffsets [8]int
+	irq        vrcIrq
+	audio      VRC7Audio
+	silenced   bool // the sound is held in reset
+}
+
+func init() {
+	registerMapper(MapperInfo{Number: 85, Name: "VRC7", Boards: []string{"VRC7a", "VRC7b"}, Audio: "VRC7"}, NewMapper85)
+}
+
+func NewMapper85(cartridge *Cartridge) Mapper {
+	mapper := Mapper85{}
+	mapper.cartridge = cartridge
+	switch cartridge.submapper {
+	case 1:
+		mapper.line = 0x08
+	case 2:
+		mapper.line = 0x10
+	default:
+		mapper.line = 0x18
+	}
+	mapper.audio.reset()
+	mapper.updateOffsets()
+	return &mapper
+}
+
+func (mapper *Mapper85) Step() {
+}
+
+//CpuStep clock the irq counter and the synthesizer
+func (mapper *Mapper85) CpuStep() {
+	mapper.irq.step(mapper.cartridge)
+	if !mapper.silenced {
+		mapper.audio.step()
+	}
+}
+
+func (mapper *Mapper85) AudioOutput() float32 {
+	if mapper.silenced {
+		return 0
+	}
+	return mapper.audio.output
+}
+
+func (mapper *Mapper85) Read(address uint16) byte {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x0400
+		offset := address % 0x0400
+		return mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)]
+	case address >= 0x8000:
+		address = address - 0x8000
+		bank := address / 0x2000
+		offset := address % 0x2000
+		return mapper.cartridge.prg[mapper.prgOffsets[bank]+int(offset)]
+	case address >= 0x6000:
+		return mapper.cartridge.sram[int(address)-0x6000]
+	default:
+		log.Fatalf("unhandled mapper85 read at address: 0x%04X", address)
+	}
+	return 0
+}
+
+func (mapper *Mapper85) Write(address uint16, value byte) bool {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x0400
+		offset := address % 0x0400
+		mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)] = value
+	case address >= 0x8000:
+		mapper.writeRegister(address, value)
+	case address >= 0x6000:
+		mapper.cartridge.sram[int(address)-0x6000] = value
+	default:
+		log.Fatalf("unhandled mapper85 write at address: 0x%04X", address)
+		return false
+	}
+	return true
+}
+
+func (mapper *Mapper85) writeRegister(address uint16, value byte) {
+	second := address&mapper.line != 0
+
+	switch address & 0xF000 {
+	case 0x8000: // PRG banks at $8000 and $A000
+		if second {
+			mapper.prgBanks[1] = value & 0x3F
+		} else {
+			mapper.prgBanks[0] = value & 0x3F
+		}
+	case 0x9000: // PRG bank at $C000, the sound ports are $9010 (register select) and $9030 (data)
+		switch {
+		case address&0x0030 == 0x0010:
+			mapper.audio.address = value
+		case address&0x0030 == 0x0030:
+			mapper.audio.writeRegister(value)
+		case !second:
+			mapper.prgBanks[2] = value & 0x3F
+		}
+	case 0xA000, 0xB000, 0xC000, 0xD000: // 1 KB CHR banks
+		index := int((address>>12)-0xA) * 2
+		if second {
+			index++
+		}
+		mapper.chrBanks[index] = value
+	case 0xE000:
+		if second {
+			mapper.irq.latch = value
+		} else {
+			mapper.writeControl(value)
+		}
+	case 0xF000:
+		if second {
+			mapper.irq.acknowledge()
+		} else {
+			mapper.irq.writeControl(value)
+		}
+	}
+	mapper.updateOffsets()
+}
+
+// Control ($E000): bits 0-1 mirroring, bit 6 silences and resets the sound
+func (mapper *Mapper85) writeControl(value byte) {
+	switch value & 3 {
+	case 0:
+		mapper.cartridge.mirror = MirrorVertical
+	case 1:
+		mapper.cartridge.mirror = MirrorHorizontal
+	case 2:
+		mapper.cartridge.mirror = MirrorSingle0
+	case 3:
+		mapper.cartridge.mirror = MirrorSingle1
+	}
+	mapper.silenced = value&0x40 != 0
+	if mapper.silenced {
+		mapper.audio.reset()
+	}
+}
+
+func (mapper *Mapper85) prgBankOffset(index int) int {
+	index %= len(mapper.cartridge.prg) / 0x2000
+	offset := index * 0x2000
+	if offset < 0 {
+		offset += len(mapper.cartridge.prg)
+	}
+	return offset
+}
+
+func (mapper *Mapper85) chrBankOffset(index int) int {
+	index %= len(mapper.cartridge.chr) / 0x0400
+	return index * 0x0400
+}
+
+// $8000, $A000 and $C000 switchable, $E000 last 8 KB bank
+func (mapper *Mapper85) updateOffsets() {
+	for i, bank := range mapper.prgBanks {
+		mapper.prgOffsets[i] = mapper.prgBankOffset(int(bank))
+	}
+	mapper.prgOffsets[3] = mapper.prgBankOffset(-1)
+	for i, bank := range mapper.chrBanks {
+		mapper.chrOffsets[i] = mapper.chrBankOffset(int(bank))
+	}
+}
@@ -0,0 +1,283 @@
+package nescomponents
+
+import (
+	"log"
+)
+
+//MapperDiscrete is a board made of discrete logic chips: latches written by the cpu select 16 or 32 KB PRG banks,
+//4 or 8 KB CHR banks and sometimes the mirroring. The boards only differ by how the writes set them,
+//each constructor gives the decoding of its board
+type MapperDiscrete struct {
+	cartridge    *Cartridge
+	prgOffsets   [2]int                           // 16 KB halves at $8000 and $C000
+	chrOffsets   [2]int                           // 4 KB halves at $0000 and $1000
+	busConflicts bool                             // the rom drives the data bus during the write too: the value is ANDed with it
+	write        func(address uint16, value byte) // the registers of the board, called for the writes at $4020-$FFFF
+	nibbles      [4]byte                          // 4 bit RAM of some multicarts, 0 if it has none
+	nibblesStart uint16                           // start of the 4 bit RAM, mirrored up to $5FFF
+}
+
+func init() {
+	registerMapper(MapperInfo{Number: 0, Name: "NROM", Boards: []string{"NROM-128", "NROM-256"}}, NewMapper0)
+	registerMapper(MapperInfo{Number: 2, Name: "UxROM", Boards: []string{"UNROM", "UOROM"}}, NewMapper2)
+	registerMapper(MapperInfo{Number: 3, Name: "CNROM", Boards: []string{"CNROM"}}, NewMapper3)
+	registerMapper(MapperInfo{Number: 11, Name: "Color Dreams"}, NewMapper11)
+	registerMapper(MapperInfo{Number: 34, Name: "BNROM/NINA-001", Boards: []string{"BNROM", "NINA-001"}}, NewMapper34)
+	registerMapper(MapperInfo{Number: 41, Name: "Caltron 6-in-1"}, NewMapper41)
+	registerMapper(MapperInfo{Number: 66, Name: "GxROM", Boards: []string{"GNROM", "MHROM"}}, NewMapper66)
+	registerMapper(MapperInfo{Number: 71, Name: "Camerica", Boards: []string{"BF9093", "BF9097"}}, NewMapper71)
+	registerMapper(MapperInfo{Number: 79, Name: "NINA-03/NINA-06", Boards: []string{"NINA-03", "NINA-06"}}, NewMapper79)
+}
+
+//newMapperDiscrete start with the first 16 KB bank at $8000, the last one at $C000 and the first 8 KB of CHR
+func newMapperDiscrete(cartridge *Cartridge) *MapperDiscrete {
+	mapper := MapperDiscrete{}
+	mapper.cartridge = cartridge
+	mapper.write = func(address uint16, value byte) {}
+	mapper.setPrg16(0, 0)
+	mapper.setPrg16(1, -1)
+	mapper.setChr8(0)
+	return &mapper
+}
+
+//NewMapper0 NROM: no register, 16 or 32 KB of PRG and 8 KB of CHR
+func NewMapper0(cartridge *Cartridge) Mapper {
+	return newMapperDiscrete(cartridge)
+}
+
+//NewMapper2 UxROM (Mega Man, Castlevania): 16 KB bank at $8000, the last one at $C000
+//the NES 2.0 submapper 2 has bus conflicts
+func NewMapper2(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.busConflicts = cartridge.submapper == 2
+	mapper.write = func(address uint16, value byte) {
+		if address >= 0x8000 {
+			mapper.setPrg16(0, int(value))
+		}
+	}
+	return mapper
+}
+
+//NewMapper3 CNROM (Gradius, Arkanoid): 8 KB CHR bank, the NES 2.0 submapper 2 has bus conflicts
+func NewMapper3(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.busConflicts = cartridge.submapper == 2
+	mapper.write = func(address uint16, value byte) {
+		if address >= 0x8000 {
+			mapper.setChr8(int(value))
+		}
+	}
+	return mapper
+}
+
+//NewMapper11 Color Dreams: 32 KB PRG bank (bits 0-1) and 8 KB CHR bank (bits 4-7), with bus conflicts
+func NewMapper11(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.busConflicts = true
+	mapper.setPrg32(0)
+	mapper.write = func(address uint16, value byte) {
+		if address >= 0x8000 {
+			mapper.setPrg32(int(value & 0x03))
+			mapper.setChr8(int(value >> 4))
+		}
+	}
+	return mapper
+}
+
+//NewMapper34 BNROM (Deadly Towers, submapper 2): 32 KB PRG bank with bus conflicts, and NINA-001
+//(Impossible Mission II, submapper 1): 32 KB PRG bank at $7FFD and two 4 KB CHR banks at $7FFE and $7FFF.
+//Without submapper, the boards with more than 8 KB of CHR are NINA-001
+func NewMapper34(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.setPrg32(0)
+	nina := cartridge.submapper == 1 || (cartridge.submapper == 0 && len(cartridge.chr) > 0x2000)
+	mapper.busConflicts = !nina
+	mapper.write = func(address uint16, value byte) {
+		switch {
+		case !nina && address >= 0x8000:
+			mapper.setPrg32(int(value))
+		case nina && address == 0x7FFD:
+			mapper.setPrg32(int(value & 0x01))
+		case nina && address == 0x7FFE:
+			mapper.setChr4(0, int(value&0x0F))
+		case nina && address == 0x7FFF:
+			mapper.setChr4(1, int(value&0x0F))
+		}
+	}
+	return mapper
+}
+
+//NewMapper41 Caltron 6-in-1: the outer register is the address written at $6000-$67FF (PRG bank, high CHR bits, mirroring),
+//the inner CHR register at $8000-$FFFF is only enabled by the bit 2 of the PRG bank
+func NewMapper41(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	var outer uint16
+	var chrBank int
+	mapper.setPrg32(0)
+	mapper.write = func(address uint16, value byte) {
+		switch {
+		case address >= 0x6000 && address < 0x6800:
+			outer = address
+			chrBank = chrBank&0x03 | int(address>>1)&0x0C
+			mapper.setPrg32(int(address & 0x07))
+			mapper.setMirroring(address&0x20 != 0)
+		case address >= 0x8000 && outer&0x04 != 0:
+			chrBank = chrBank&0x0C | int(value&0x03)
+		default:
+			return
+		}
+		mapper.setChr8(chrBank)
+	}
+	return mapper
+}
+
+//NewMapper66 GxROM (Super Mario Bros. + Duck Hunt): 32 KB PRG bank (bits 4-5) and 8 KB CHR bank (bits 0-1), with bus conflicts
+func NewMapper66(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.busConflicts = true
+	mapper.setPrg32(0)
+	mapper.write = func(address uint16, value byte) {
+		if address >= 0x8000 {
+			mapper.setPrg32(int(value>>4) & 0x03)
+			mapper.setChr8(int(value & 0x03))
+		}
+	}
+	return mapper
+}
+
+//NewMapper71 Camerica/Codemasters (Micro Machines, Bee 52): 16 KB bank at $8000 written at $C000-$FFFF, the last one at $C000.
+//Fire Hawk (submapper 1) selects a single-screen mirroring at $8000-$9FFF, the other boards have a fixed mirroring:
+//without submapper, the writes at $9000-$9FFF are taken as mirroring
+func NewMapper71(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mirroringStart := uint16(0x9000)
+	if cartridge.submapper == 1 {
+		mirroringStart = 0x8000
+	}
+	mapper.write = func(address uint16, value byte) {
+		switch {
+		case address >= 0xC000:
+			mapper.setPrg16(0, int(value&0x0F))
+		case address >= mirroringStart && address < 0xA000:
+			if value&0x10 == 0 {
+				mapper.cartridge.mirror = MirrorSingle0
+			} else {
+				mapper.cartridge.mirror = MirrorSingle1
+			}
+		}
+	}
+	return mapper
+}
+
+//NewMapper79 NINA-03/NINA-06 (American Video Entertainment): the register is at $4100, mirrored in $4100-$5FFF
+//where A8 is set: 32 KB PRG bank (bit 3) and 8 KB CHR bank (bits 0-2)
+func NewMapper79(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.setPrg32(0)
+	mapper.write = func(address uint16, value byte) {
+		if address&0xE100 == 0x4100 {
+			mapper.setPrg32(int(value>>3) & 0x01)
+			mapper.setChr8(int(value & 0x07))
+		}
+	}
+	return mapper
+}
+
+func (mapper *MapperDiscrete) Step() {
+}
+
+func (mapper *MapperDiscrete) Read(address uint16) byte {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x1000
+		offset := address % 0x1000
+		return mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)]
+	case address >= 0x8000:
+		address = address - 0x8000
+		bank := address / 0x4000
+		offset := address % 0x4000
+		return mapper.cartridge.prg[mapper.prgOffsets[bank]+int(offset)]
+	case address >= 0x6000:
+		return mapper.cartridge.sram[int(address)-0x6000]
+	default:
+		log.Fatalf("unhandled discrete mapper read at address: 0x%04X", address)
+	}
+	return 0
+}
+
+func (mapper *MapperDiscrete) Write(address uint16, value byte) bool {
+	switch {
+	case address < 0x2000:
+		bank := address / 0x1000
+		offset := address % 0x1000
+		mapper.cartridge.chr[mapper.chrOffsets[bank]+int(offset)] = value
+	case address >= 0x8000:
+		if mapper.busConflicts {
+			value &= mapper.Read(address)
+		}
+		mapper.write(address, value)
+	case address >= 0x6000:
+		mapper.cartridge.sram[int(address)-0x6000] = value
+		mapper.write(address, value)
+	default:
+		log.Fatalf("unhandled discrete mapper write at address: 0x%04X", address)
+		return false
+	}
+	return true
+}
+
+// $4020-$5FFF: the 4 bit RAM of the multicarts, open bus elsewhere
+func (mapper *MapperDiscrete) ReadExpansion(address uint16) byte {
+	if mapper.nibblesStart != 0 && address >= mapper.nibblesStart {
+		return mapper.nibbles[address&3] & 0x0F
+	}
+	return 0
+}
+
+func (mapper *MapperDiscrete) WriteExpansion(address uint16, value byte) {
+	if mapper.nibblesStart != 0 && address >= mapper.nibblesStart {
+		mapper.nibbles[address&3] = value & 0x0F
+	}
+	mapper.write(address, value)
+}
+
+//bankOffset return the offset of a bank in a rom, negative banks count from the end
+func bankOffset(rom []byte, index int, size int) int {
+	index %= len(rom) / size
+	offset := index * size
+	if offset < 0 {
+		offset += len(rom)
+	}
+	return offset
+}
+
+//setPrg16 map a 16 KB PRG bank at $8000 (slot 0) or $C000 (slot 1)
+func (mapper *MapperDiscrete) setPrg16(slot int, bank int) {
+	mapper.prgOffsets[slot] = bankOffset(mapper.cartridge.prg, bank, 0x4000)
+}
+
+//setPrg32 map a 32 KB PRG bank at $8000
+func (mapper *MapperDiscrete) setPrg32(bank int) {
+	mapper.setPrg16(0, bank*2)
+	mapper.setPrg16(1, bank*2+1)
+}
+
+//setChr4 map a 4 KB CHR bank at $0000 (slot 0) or $1000 (slot 1)
+func (mapper *MapperDiscrete) setChr4(slot int, bank int) {
+	mapper.chrOffsets[slot] = bankOffset(mapper.cartridge.chr, bank, 0x1000)
+}
+
+//setChr8 map an 8 KB CHR bank
+func (mapper *MapperDiscrete) setChr8(bank int) {
+	mapper.setChr4(0, bank*2)
+	mapper.setChr4(1, bank*2+1)
+}
+
+//setMirroring select the horizontal or the vertical mirroring
+func (mapper *MapperDiscrete) setMirroring(horizontal bool) {
+	if horizontal {
+		mapper.cartridge.mirror = MirrorHorizontal
+	} else {
+		mapper.cartridge.mirror = MirrorVertical
+	}
+}
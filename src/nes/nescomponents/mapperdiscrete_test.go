@@ -0,0 +1,141 @@
+package nescomponents
+
+import (
+	"testing"
+)
+
+//newTestCartridge build a cartridge whose every 16 KB PRG bank is filled with its number, except its last 16 bytes
+//which are $FF so a write there has no bus conflict, and whose every 4 KB CHR bank is filled with its number
+func newTestCartridge(mapperType byte, submapper byte, prgBanks int, chrBanks int) *Cartridge {
+	cartridge := Cartridge{mapperType: mapperType, submapper: submapper, mirror: MirrorVertical}
+
+	cartridge.prg = make([]byte, prgBanks*0x4000)
+	for i := range cartridge.prg {
+		cartridge.prg[i] = byte(i / 0x4000)
+		if i%0x4000 >= 0x3FF0 {
+			cartridge.prg[i] = 0xFF
+		}
+	}
+	cartridge.chr = make([]byte, chrBanks*0x2000)
+	for i := range cartridge.chr {
+		cartridge.chr[i] = byte(i / 0x1000)
+	}
+	cartridge.sram = make([]byte, 0x2000)
+	return &cartridge
+}
+
+//mapperWrite is a write of the cpu, below $6000 it goes to the expansion area
+type mapperWrite struct {
+	address uint16
+	value   byte
+}
+
+func writeMapper(t *testing.T, mapper Mapper, write mapperWrite) {
+	if write.address >= 0x6000 {
+		mapper.Write(write.address, write.value)
+		return
+	}
+	expansion, ok := mapper.(ExpansionMapper)
+	if !ok {
+		t.Fatalf("write at $%04X: the mapper has no expansion registers", write.address)
+	}
+	expansion.WriteExpansion(write.address, write.value)
+}
+
+var discreteMapperTests = []struct {
+	name      string
+	mapper    byte
+	submapper byte
+	prgBanks  int // 16 KB
+	chrBanks  int // 8 KB
+	writes    []mapperWrite
+	prg       [2]byte // PRG banks read at $8000 and $C000
+	chr       [2]byte // CHR banks read at $0000 and $1000
+	mirror    byte
+}{
+	{"color dreams", 11, 0, 8, 16, []mapperWrite{{0xFFF0, 0x52}}, [2]byte{4, 5}, [2]byte{10, 11}, MirrorVertical},
+	{"color dreams bus conflict", 11, 0, 8, 16, []mapperWrite{{0xC000, 0xFF}}, [2]byte{2, 3}, [2]byte{0, 1}, MirrorVertical},
+	{"bnrom", 34, 2, 8, 1, []mapperWrite{{0xFFF0, 0x03}}, [2]byte{6, 7}, [2]byte{0, 1}, MirrorVertical},
+	{"bnrom bus conflict", 34, 2, 8, 1, []mapperWrite{{0xC000, 0x03}}, [2]byte{2, 3}, [2]byte{0, 1}, MirrorVertical},
+	{"nina-001", 34, 1, 4, 8, []mapperWrite{{0x7FFD, 0x01}, {0x7FFE, 0x05}, {0x7FFF, 0x1A}}, [2]byte{2, 3}, [2]byte{5, 10}, MirrorVertical},
+	{"nina-001 without submapper", 34, 0, 4, 8, []mapperWrite{{0x7FFD, 0x01}, {0x7FFE, 0x05}, {0x7FFF, 0x1A}}, [2]byte{2, 3}, [2]byte{5, 10}, MirrorVertical},
+	{"caltron", 41, 0, 16, 16, []mapperWrite{{0x603C, 0}, {0xFFF0, 0x02}}, [2]byte{8, 9}, [2]byte{28, 29}, MirrorHorizontal},
+	{"caltron inner register disabled", 41, 0, 16, 16, []mapperWrite{{0x6000, 0}, {0x8000, 0x03}}, [2]byte{0, 1}, [2]byte{0, 1}, MirrorVertical},
+	{"58 16 KB mode", 58, 0, 16, 8, []mapperWrite{{0x80DD, 0}}, [2]byte{5, 5}, [2]byte{6, 7}, MirrorHorizontal},
+	{"58 32 KB mode", 58, 0, 16, 8, []mapperWrite{{0x8005, 0}}, [2]byte{4, 5}, [2]byte{0, 1}, MirrorVertical},
+	{"gxrom", 66, 0, 8, 4, []mapperWrite{{0xFFF0, 0x21}}, [2]byte{4, 5}, [2]byte{2, 3}, MirrorVertical},
+	{"gxrom bus conflict", 66, 0, 8, 4, []mapperWrite{{0xC000, 0x33}}, [2]byte{0, 1}, [2]byte{2, 3}, MirrorVertical},
+	{"camerica", 71, 0, 8, 1, []mapperWrite{{0xC000, 0x03}, {0x8000, 0x10}}, [2]byte{3, 7}, [2]byte{0, 1}, MirrorVertical},
+	{"camerica mirroring", 71, 0, 8, 1, []mapperWrite{{0x9000, 0x00}}, [2]byte{0, 7}, [2]byte{0, 1}, MirrorSingle0},
+	{"fire hawk", 71, 1, 8, 1, []mapperWrite{{0x8000, 0x10}, {0xC000, 0x05}}, [2]byte{5, 7}, [2]byte{0, 1}, MirrorSingle1},
+	{"nina-03", 79, 0, 4, 8, []mapperWrite{{0x4100, 0x0D}}, [2]byte{2, 3}, [2]byte{10, 11}, MirrorVertical},
+	{"nina-03 mirrored register", 79, 0, 4, 8, []mapperWrite{{0x5F00, 0x02}}, [2]byte{0, 1}, [2]byte{4, 5}, MirrorVertical},
+	{"nina-03 not a register", 79, 0, 4, 8, []mapperWrite{{0x4200, 0x0F}}, [2]byte{0, 1}, [2]byte{0, 1}, MirrorVertical},
+	{"200", 200, 0, 8, 8, []mapperWrite{{0x800D, 0}}, [2]byte{5, 5}, [2]byte{10, 11}, MirrorHorizontal},
+	{"201", 201, 0, 8, 4, []mapperWrite{{0x800B, 0}}, [2]byte{6, 7}, [2]byte{6, 7}, MirrorVertical},
+	{"201 without A3", 201, 0, 8, 4, []mapperWrite{{0x800B, 0}, {0x8003, 0}}, [2]byte{0, 1}, [2]byte{0, 1}, MirrorVertical},
+	{"202", 202, 0, 8, 8, []mapperWrite{{0x8006, 0}}, [2]byte{3, 3}, [2]byte{6, 7}, MirrorVertical},
+	{"202 32 KB", 202, 0, 8, 8, []mapperWrite{{0x8009, 0}}, [2]byte{4, 5}, [2]byte{8, 9}, MirrorHorizontal},
+	{"202 horizontal", 202, 0, 8, 8, []mapperWrite{{0x8007, 0}}, [2]byte{3, 3}, [2]byte{6, 7}, MirrorHorizontal},
+	{"212 16 KB mode", 212, 0, 16, 8, []mapperWrite{{0x800E, 0}}, [2]byte{6, 6}, [2]byte{12, 13}, MirrorHorizontal},
+	{"212 32 KB mode", 212, 0, 16, 8, []mapperWrite{{0xC005, 0}}, [2]byte{4, 5}, [2]byte{10, 11}, MirrorVertical},
+	{"225 16 KB mode", 225, 0, 128, 128, []mapperWrite{{0xB56A, 0}}, [2]byte{21, 21}, [2]byte{84, 85}, MirrorHorizontal},
+	{"225 32 KB mode", 225, 0, 128, 128, []mapperWrite{{0x8543, 0}}, [2]byte{20, 21}, [2]byte{6, 7}, MirrorVertical},
+	{"225 high bit", 225, 0, 128, 128, []mapperWrite{{0xD142, 0}}, [2]byte{69, 69}, [2]byte{132, 133}, MirrorVertical},
+	{"action 52 16 KB mode", 228, 0, 96, 64, []mapperWrite{{0xA8E5, 0x02}}, [2]byte{35, 35}, [2]byte{44, 45}, MirrorHorizontal},
+	{"action 52 chip 3", 228, 0, 96, 64, []mapperWrite{{0x9900, 0x01}}, [2]byte{68, 69}, [2]byte{2, 3}, MirrorVertical},
+}
+
+func TestDiscreteMappers(t *testing.T) {
+	for _, test := range discreteMapperTests {
+		cartridge := newTestCartridge(test.mapper, test.submapper, test.prgBanks, test.chrBanks)
+		mapper, err := NewMapper(cartridge)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		for _, write := range test.writes {
+			writeMapper(t, mapper, write)
+		}
+		prg := [2]byte{mapper.Read(0x8000), mapper.Read(0xC000)}
+		chr := [2]byte{mapper.Read(0x0000), mapper.Read(0x1000)}
+		if prg != test.prg {
+			t.Errorf("%s: PRG banks %v, want %v", test.name, prg, test.prg)
+		}
+		if chr != test.chr {
+			t.Errorf("%s: CHR banks %v, want %v", test.name, chr, test.chr)
+		}
+		if cartridge.mirror != test.mirror {
+			t.Errorf("%s: mirroring %d, want %d", test.name, cartridge.mirror, test.mirror)
+		}
+	}
+}
+
+//TestMulticartNibbles the 4 bit RAM keeps the low nibble of the value, mirrored every 4 bytes from its start
+func TestMulticartNibbles(t *testing.T) {
+	tests := []struct {
+		mapper byte
+		write  uint16
+		read   uint16
+		below  uint16 // an address below the RAM, open bus
+	}{
+		{225, 0x5800, 0x5FFC, 0x5000},
+		{228, 0x4021, 0x5FFD, 0},
+	}
+
+	for _, test := range tests {
+		mapper, err := NewMapper(newTestCartridge(test.mapper, 0, 8, 8))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expansion := mapper.(ExpansionMapper)
+		expansion.WriteExpansion(test.write, 0xAB)
+		if value := expansion.ReadExpansion(test.read); value != 0x0B {
+			t.Errorf("mapper %d: $%04X reads $%02X, want $0B", test.mapper, test.read, value)
+		}
+		if test.below != 0 {
+			if value := expansion.ReadExpansion(test.below); value != 0 {
+				t.Errorf("mapper %d: $%04X reads $%02X, want open bus", test.mapper, test.below, value)
+			}
+		}
+	}
+}
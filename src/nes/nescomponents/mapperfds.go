@@ -0,0 +1,364 @@
+package nescomponents
+
+import (
+	"bytes"
+	"errors"
+	"log"
+)
+
+//the drive of the Famicom Disk System reads or writes a byte every fdsCyclesPerByte cpu cycles (96.4 kbit/s), the head
+//goes back to the start of the side fdsRewindCycles after the motor is started. A switched side is in the drive
+//fdsInsertCycles after the disk was ejected, the time the BIOS needs to see there is no disk
+const (
+	fdsCyclesPerByte = 149
+	fdsRewindCycles  = 50000
+	fdsInsertCycles  = 1789773
+	fdsMapperType    = 20 // iNES number reserved for the Famicom Disk System, it is not built by NewMapper
+)
+
+//MapperFds is the RAM adapter of the Famicom Disk System: 32 KB of PRG-RAM at $6000-$DFFF, the 8 KB BIOS at $E000,
+//8 KB of CHR-RAM, a cpu cycle irq timer, the disk drive and a wavetable sound channel
+//https://wiki.nesdev.com/w/index.php/Family_Computer_Disk_System
+type MapperFds struct {
+	cartridge      *Cartridge
+	disk           *fdsDisk
+	original       []byte   // the disk image as loaded, the changes are saved against it
+	tracks         [][]byte // the sides as the drive reads them
+	written        []bool   // the sides written by the game
+	side           int
+	inserted       bool
+	insertDelay    int    // cpu cycles before the next side is in the drive
+	diskRegisters  bool   // $4023 bit 0
+	soundRegisters bool   // $4023 bit 1
+	timerReload    uint16 // $4020/$4021
+	timerCounter   uint16
+	timerRepeat    bool
+	timerEnabled   bool
+	timerIrq       bool
+	diskIrq        bool
+	motorOn        bool // $4025 bit 0
+	resetTransfer  bool // $4025 bit 1
+	readMode       bool // $4025 bit 2
+	crcControl     bool // $4025 bit 4: the CRC is read or written
+	diskReady      bool // $4025 bit 6
+	diskIrqEnabled bool // $4025 bit 7
+	position       int  // byte of the track under the head
+	delay          int  // cpu cycles before the next byte
+	endOfHead      bool
+	scanning       bool
+	gapEnded       bool // the $80 mark starting a block was read
+	transferred    bool // a byte was read or written, $4030 bit 1
+	readData       byte
+	writeData      byte
+	lastCrcControl bool
+	crc            uint16
+	audio          FdsAudio
+}
+
+//newMapperFds insert the first side of the disk in the drive
+func newMapperFds(cartridge *Cartridge, disk *fdsDisk) *MapperFds {
+	mapper := MapperFds{}
+	mapper.cartridge = cartridge
+	mapper.audio = newFdsAudio()
+	mapper.loadDisk(disk)
+	mapper.original = disk.image()
+	mapper.inserted = true
+	return &mapper
+}
+
+//loadDisk build the tracks of the sides, none of them is written yet
+func (mapper *MapperFds) loadDisk(disk *fdsDisk) {
+	mapper.disk = disk
+	mapper.tracks = make([][]byte, len(disk.sides))
+	mapper.written = make([]bool, len(disk.sides))
+	for side := range disk.sides {
+		mapper.tracks[side] = disk.track(side)
+	}
+}
+
+func (mapper *MapperFds) Step() {
+}
+
+func (mapper *MapperFds) Read(address uint16) byte {
+	switch {
+	case address < 0x2000:
+		return mapper.cartridge.chr[address]
+	case address >= 0xE000:
+		return mapper.cartridge.prg[int(address-0xE000)%len(mapper.cartridge.prg)]
+	case address >= 0x6000:
+		return mapper.cartridge.sram[address-0x6000]
+	default:
+		log.Fatalf("unhandled fds read at address: 0x%04X", address)
+	}
+	return 0
+}
+
+func (mapper *MapperFds) Write(address uint16, value byte) bool {
+	switch {
+	case address < 0x2000:
+		mapper.cartridge.chr[address] = value
+	case address >= 0xE000:
+		// BIOS
+	case address >= 0x6000:
+		mapper.cartridge.sram[address-0x6000] = value
+	default:
+		log.Fatalf("unhandled fds write at address: 0x%04X", address)
+		return false
+	}
+	return true
+}
+
+//CpuStep clock the irq timer, the drive and the sound
+func (mapper *MapperFds) CpuStep() {
+	if mapper.timerEnabled {
+		if mapper.timerCounter == 0 {
+			mapper.timerIrq = true
+			mapper.timerCounter = mapper.timerReload
+			mapper.timerEnabled = mapper.timerRepeat
+		} else {
+			mapper.timerCounter--
+		}
+	}
+	if mapper.insertDelay > 0 {
+		mapper.insertDelay--
+		if mapper.insertDelay == 0 {
+			mapper.inserted = true
+		}
+	}
+	mapper.stepDrive()
+	if mapper.timerIrq || mapper.diskIrq {
+		mapper.cartridge.TriggerIRQ()
+	}
+	mapper.audio.step()
+}
+
+//stepDrive move the disk under the head: a byte is read or written every fdsCyclesPerByte cpu cycles while the motor runs.
+//In read mode the bytes are transferred from the $80 mark of a block, in write mode the CRC accumulated since the mark
+//is written while the CRC control bit is set
+func (mapper *MapperFds) stepDrive() {
+	if !mapper.inserted || !mapper.motorOn {
+		mapper.endOfHead = true
+		mapper.scanning = false
+		return
+	}
+	if mapper.resetTransfer && !mapper.scanning {
+		return
+	}
+	if mapper.endOfHead {
+		mapper.delay = fdsRewindCycles
+		mapper.endOfHead = false
+		mapper.position = 0
+		mapper.gapEnded = false
+		return
+	}
+	if mapper.delay > 0 {
+		mapper.delay--
+		return
+	}
+	mapper.scanning = true
+	track := mapper.tracks[mapper.side]
+	irq := mapper.diskIrqEnabled
+	if mapper.readMode {
+		value := track[mapper.position]
+		if !mapper.lastCrcControl {
+			mapper.crc = crcByte(mapper.crc, value)
+		}
+		if !mapper.diskReady {
+			mapper.gapEnded = false
+			mapper.crc = 0
+		} else if value != 0 && !mapper.gapEnded {
+			mapper.gapEnded = true
+			irq = false
+		}
+		if mapper.gapEnded {
+			mapper.transferred = true
+			mapper.readData = value
+			mapper.diskIrq = mapper.diskIrq || irq
+		}
+	} else {
+		value := byte(0)
+		if !mapper.crcControl {
+			mapper.transferred = true
+			value = mapper.writeData
+			mapper.diskIrq = mapper.diskIrq || irq
+		}
+		if !mapper.diskReady {
+			value = 0
+		}
+		if !mapper.crcControl {
+			mapper.crc = crcByte(mapper.crc, value)
+		} else {
+			if !mapper.lastCrcControl {
+				mapper.crc = crcByte(crcByte(mapper.crc, 0), 0)
+			}
+			value = byte(mapper.crc)
+			mapper.crc >>= 8
+		}
+		track[mapper.position] = value
+		mapper.written[mapper.side] = true
+		mapper.gapEnded = false
+	}
+	mapper.lastCrcControl = mapper.crcControl
+	mapper.position++
+	if mapper.position >= len(track) {
+		mapper.motorOn = false
+	} else {
+		mapper.delay = fdsCyclesPerByte
+	}
+}
+
+//AudioOutput the sound channel, muted while the sound registers are disabled
+func (mapper *MapperFds) AudioOutput() float32 {
+	if !mapper.soundRegisters {
+		return 0
+	}
+	return mapper.audio.audioOutput()
+}
+
+// $4030: disk status (bit 0 timer irq, bit 1 byte transferred), reading it acknowledges the irqs
+// $4031: data read from the disk, $4032: drive status, $4033: battery good
+// $4040-$4097: sound
+func (mapper *MapperFds) ReadExpansion(address uint16) byte {
+	switch {
+	case address == 0x4030 && mapper.diskRegisters:
+		value := byte(0)
+		if mapper.timerIrq {
+			value |= 0x01
+		}
+		if mapper.transferred {
+			value |= 0x02
+		}
+		mapper.transferred = false
+		mapper.timerIrq = false
+		mapper.diskIrq = false
+		return value
+	case address == 0x4031 && mapper.diskRegisters:
+		mapper.transferred = false
+		mapper.diskIrq = false
+		return mapper.readData
+	case address == 0x4032 && mapper.diskRegisters:
+		// bit 0 no disk, bit 1 not ready, bit 2 write protected
+		value := byte(0x40)
+		if !mapper.inserted {
+			value |= 0x05
+		}
+		if !mapper.inserted || !mapper.scanning {
+			value |= 0x02
+		}
+		return value
+	case address == 0x4033 && mapper.diskRegisters:
+		return 0x80
+	case address >= 0x4040 && address <= 0x4097 && mapper.soundRegisters:
+		return mapper.audio.read(address)
+	}
+	return 0
+}
+
+func (mapper *MapperFds) WriteExpansion(address uint16, value byte) {
+	switch {
+	case address == 0x4020 && mapper.diskRegisters:
+		mapper.timerReload = mapper.timerReload&0xFF00 | uint16(value)
+	case address == 0x4021 && mapper.diskRegisters:
+		mapper.timerReload = mapper.timerReload&0x00FF | uint16(value)<<8
+	case address == 0x4022 && mapper.diskRegisters:
+		// bit 0 repeat, bit 1 enable: reload the counter, or acknowledge the irq
+		mapper.timerRepeat = value&0x01 != 0
+		mapper.timerEnabled = value&0x02 != 0
+		if mapper.timerEnabled {
+			mapper.timerCounter = mapper.timerReload
+		} else {
+			mapper.timerIrq = false
+		}
+	case address == 0x4023:
+		// bit 0 disk registers, bit 1 sound registers
+		mapper.diskRegisters = value&0x01 != 0
+		mapper.soundRegisters = value&0x02 != 0
+		if !mapper.diskRegisters {
+			mapper.timerEnabled = false
+			mapper.timerIrq = false
+			mapper.diskIrq = false
+		}
+	case address == 0x4024 && mapper.diskRegisters:
+		mapper.writeData = value
+		mapper.transferred = false
+		mapper.diskIrq = false
+	case address == 0x4025 && mapper.diskRegisters:
+		mapper.writeControl(value)
+	case address >= 0x4040 && address <= 0x4097 && mapper.soundRegisters:
+		mapper.audio.write(address, value)
+	}
+}
+
+// $4025: bit 0 motor on, bit 1 reset transfer, bit 2 read mode, bit 3 horizontal mirroring, bit 4 CRC control,
+// bit 6 disk ready (transfer started), bit 7 disk irq enabled. Writing it acknowledges the disk irq
+func (mapper *MapperFds) writeControl(value byte) {
+	mapper.motorOn = value&0x01 != 0
+	mapper.resetTransfer = value&0x02 != 0
+	mapper.readMode = value&0x04 != 0
+	if value&0x08 != 0 {
+		mapper.cartridge.mirror = MirrorHorizontal
+	} else {
+		mapper.cartridge.mirror = MirrorVertical
+	}
+	mapper.crcControl = value&0x10 != 0
+	mapper.diskReady = value&0x40 != 0
+	mapper.diskIrqEnabled = value&0x80 != 0
+	mapper.diskIrq = false
+}
+
+//InsertNextSide eject the disk and insert the next side, side B after side A and the next disk after side B
+func (mapper *MapperFds) InsertNextSide() {
+	mapper.side = (mapper.side + 1) % len(mapper.tracks)
+	mapper.inserted = false
+	mapper.insertDelay = fdsInsertCycles
+}
+
+//EjectDisk eject the disk, or insert it back when it is out
+func (mapper *MapperFds) EjectDisk() {
+	mapper.insertDelay = 0
+	mapper.inserted = !mapper.inserted
+}
+
+//image return the disk image with what the game wrote, the sides written are read back from their tracks
+func (mapper *MapperFds) image() []byte {
+	var image []byte
+
+	for side, track := range mapper.tracks {
+		if mapper.written[side] {
+			image = append(image, readTrack(track)...)
+		} else {
+			image = append(image, mapper.disk.sides[side]...)
+		}
+	}
+	return image
+}
+
+//DiskChanges return the changes to the disk image as an IPS patch, empty when the game wrote nothing
+func (mapper *MapperFds) DiskChanges() []byte {
+	image := mapper.image()
+	if bytes.Equal(image, mapper.original) {
+		return nil
+	}
+	return makeIps(mapper.original, image)
+}
+
+//ApplyDiskChanges apply the changes saved by the last game to the disk in the drive, the original image is kept to save
+//the changes of this game against it
+func (mapper *MapperFds) ApplyDiskChanges(changes []byte) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	image, err := applyIps(mapper.original, changes)
+	if err != nil {
+		return err
+	}
+	if len(image) != len(mapper.original) {
+		return errors.New("the disk changes are not made for this disk")
+	}
+	disk := fdsDisk{}
+	for ; len(image) > 0; image = image[fdsSideSize:] {
+		disk.sides = append(disk.sides, image[:fdsSideSize])
+	}
+	mapper.loadDisk(&disk)
+	return nil
+}
@@ -0,0 +1,143 @@
+package nescomponents
+
+//the pirate multicarts latch the address of the write to $8000-$FFFF instead of its value: the bits of the address
+//select the banks and the mirroring of the game picked in the menu. They are discrete boards too
+//https://wiki.nesdev.com/w/index.php/Category:Multicart_mappers
+
+//setPrgMode map the same 16 KB bank twice, or the 32 KB bank containing it
+func (mapper *MapperDiscrete) setPrgMode(bank int, mode16 bool) {
+	if mode16 {
+		mapper.setPrg16(0, bank)
+		mapper.setPrg16(1, bank)
+	} else {
+		mapper.setPrg32(bank >> 1)
+	}
+}
+
+func init() {
+	registerMapper(MapperInfo{Number: 58, Name: "68-in-1 multicart"}, NewMapper58)
+	registerMapper(MapperInfo{Number: 200, Name: "36-in-1 multicart"}, NewMapper200)
+	registerMapper(MapperInfo{Number: 201, Name: "21-in-1 multicart"}, NewMapper201)
+	registerMapper(MapperInfo{Number: 202, Name: "150-in-1 multicart"}, NewMapper202)
+	registerMapper(MapperInfo{Number: 212, Name: "Super HiK 300-in-1 multicart"}, NewMapper212)
+	registerMapper(MapperInfo{Number: 225, Name: "64-in-1 multicart"}, NewMapper225)
+	registerMapper(MapperInfo{Number: 228, Name: "Action 52"}, NewMapper228)
+}
+
+//NewMapper58 (68-in-1, Study & Game 32-in-1): A0-A2 PRG bank, A3-A5 CHR bank, A6 16 KB mode, A7 horizontal mirroring
+func NewMapper58(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.setPrg32(0)
+	mapper.write = func(address uint16, value byte) {
+		if address >= 0x8000 {
+			mapper.setPrgMode(int(address&0x07), address&0x40 != 0)
+			mapper.setChr8(int(address>>3) & 0x07)
+			mapper.setMirroring(address&0x80 != 0)
+		}
+	}
+	return mapper
+}
+
+//NewMapper200 (1200-in-1, 36-in-1): A0-A2 16 KB PRG bank mapped twice and CHR bank, A3 horizontal mirroring
+func NewMapper200(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.setPrgMode(0, true)
+	mapper.write = func(address uint16, value byte) {
+		if address >= 0x8000 {
+			mapper.setPrgMode(int(address&0x07), true)
+			mapper.setChr8(int(address & 0x07))
+			mapper.setMirroring(address&0x08 != 0)
+		}
+	}
+	return mapper
+}
+
+//NewMapper201 (8-in-1, 21-in-1): with A3 set, A0-A1 select the 32 KB PRG bank and the CHR bank, else the first ones
+func NewMapper201(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.setPrg32(0)
+	mapper.write = func(address uint16, value byte) {
+		if address >= 0x8000 {
+			bank := 0
+			if address&0x08 != 0 {
+				bank = int(address & 0x03)
+			}
+			mapper.setPrg32(bank)
+			mapper.setChr8(bank)
+		}
+	}
+	return mapper
+}
+
+//NewMapper202 (150-in-1): A1-A3 PRG and CHR bank, A0 horizontal mirroring. With A0 and A3 set, the 16 KB bank at $C000
+//is the bank with its low bit set instead of the same bank
+func NewMapper202(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.setPrgMode(0, true)
+	mapper.write = func(address uint16, value byte) {
+		if address >= 0x8000 {
+			bank := int(address>>1) & 0x07
+			mapper.setPrg16(0, bank)
+			if address&0x09 == 0x09 {
+				mapper.setPrg16(1, bank|1)
+			} else {
+				mapper.setPrg16(1, bank)
+			}
+			mapper.setChr8(bank)
+			mapper.setMirroring(address&0x01 != 0)
+		}
+	}
+	return mapper
+}
+
+//NewMapper212 (Super HiK 300-in-1): A0-A2 PRG and CHR bank, A3 horizontal mirroring, A14 32 KB mode
+func NewMapper212(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.setPrg32(0)
+	mapper.write = func(address uint16, value byte) {
+		if address >= 0x8000 {
+			mapper.setPrgMode(int(address&0x07), address&0x4000 == 0)
+			mapper.setChr8(int(address & 0x07))
+			mapper.setMirroring(address&0x08 != 0)
+		}
+	}
+	return mapper
+}
+
+//NewMapper225 (52 Games, 64-in-1): A6-A11 PRG bank, A12 16 KB mode, A13 horizontal mirroring, A0-A5 CHR bank,
+//A14 the high bit of both banks. It has 4 nibbles of RAM at $5800-$5FFF
+func NewMapper225(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.setPrg32(0)
+	mapper.nibblesStart = 0x5800
+	mapper.write = func(address uint16, value byte) {
+		if address >= 0x8000 {
+			high := int(address>>8) & 0x40
+			mapper.setPrgMode(int(address>>6)&0x3F|high, address&0x1000 != 0)
+			mapper.setChr8(int(address&0x3F) | high)
+			mapper.setMirroring(address&0x2000 != 0)
+		}
+	}
+	return mapper
+}
+
+//NewMapper228 Action 52 (and Cheetahmen II): A11-A12 PRG chip (there is no chip 2, chip 3 is the third 512 KB of the rom),
+//A6-A10 PRG bank in the chip, A5 16 KB mode, A13 horizontal mirroring, A0-A3 and the value bits 0-1 CHR bank.
+//It has 4 nibbles of RAM at $4020-$5FFF
+func NewMapper228(cartridge *Cartridge) Mapper {
+	mapper := newMapperDiscrete(cartridge)
+	mapper.setPrg32(0)
+	mapper.nibblesStart = 0x4020
+	mapper.write = func(address uint16, value byte) {
+		if address >= 0x8000 {
+			chip := int(address>>11) & 0x03
+			if chip == 3 {
+				chip = 2
+			}
+			mapper.setPrgMode(int(address>>6)&0x1F|chip<<5, address&0x20 != 0)
+			mapper.setChr8(int(address&0x0F)<<2 | int(value&0x03))
+			mapper.setMirroring(address&0x2000 != 0)
+		}
+	}
+	return mapper
+}
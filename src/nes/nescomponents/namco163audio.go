@@ -0,0 +1,98 @@
+package nescomponents
+
+//the Namco 163 sound plays up to 8 wavetable channels of 4 bit samples stored in its 128 bytes of internal RAM,
+//the registers of the channels are at the end of the same RAM. The channels take turns on the output, one every
+//15 cpu cycles, the average of the turns is what is heard
+//https://wiki.nesdev.com/w/index.php/Namco_163_audio
+const (
+	namcoCyclesPerChannel = 15
+	namcoOutputStep       = 0.0012 // level of one step of sample x volume, on the scale of the apu mixer
+)
+
+//Namco163Audio is the sound of the Namco 163
+type Namco163Audio struct {
+	ram       [128]byte // samples and channel registers, battery-backed on some boards
+	address   byte      // RAM address of the data port
+	increment bool      // the address moves after each access of the data port
+	cycle     int       // cpu cycles since the last channel update
+	current   int       // channel updated next, from 7 down to the first enabled one
+	outputs   [8]int    // last output of each channel
+}
+
+//channels return the number of enabled channels, from 1 to 8
+func (audio *Namco163Audio) channels() int {
+	return int(audio.ram[0x7F]>>4&7) + 1
+}
+
+//read and write the RAM through the data port ($4800)
+func (audio *Namco163Audio) read() byte {
+	value := audio.ram[audio.address]
+
+	audio.moveAddress()
+	return value
+}
+
+func (audio *Namco163Audio) write(value byte) {
+	audio.ram[audio.address] = value
+	audio.moveAddress()
+}
+
+func (audio *Namco163Audio) moveAddress() {
+	if audio.increment {
+		audio.address = (audio.address + 1) & 0x7F
+	}
+}
+
+// Address port ($F800): bits 0-6 RAM address, bit 7 auto increment
+func (audio *Namco163Audio) writeAddress(value byte) {
+	audio.address = value & 0x7F
+	audio.increment = value&0x80 != 0
+}
+
+//step update a channel every namcoCyclesPerChannel cpu cycles
+func (audio *Namco163Audio) step() {
+	audio.cycle++
+	if audio.cycle < namcoCyclesPerChannel {
+		return
+	}
+	audio.cycle = 0
+	first := 8 - audio.channels()
+	if audio.current < first {
+		audio.current = 7
+	}
+	audio.stepChannel(audio.current)
+	audio.current--
+}
+
+//stepChannel move the phase of a channel (registers at $40 + 8 x channel) and read its sample:
+//+0/+2/+4 frequency (18 bits), +1/+3/+5 phase (24 bits), +4 length, +6 wave address, +7 volume
+func (audio *Namco163Audio) stepChannel(channel int) {
+	registers := audio.ram[0x40+channel*8 : 0x48+channel*8]
+	frequency := uint32(registers[0]) | uint32(registers[2])<<8 | uint32(registers[4]&3)<<16
+	phase := uint32(registers[1]) | uint32(registers[3])<<8 | uint32(registers[5])<<16
+	length := (256 - uint32(registers[4]&0xFC)) << 16
+
+	phase = (phase + frequency) % length
+	registers[1] = byte(phase)
+	registers[3] = byte(phase >> 8)
+	registers[5] = byte(phase >> 16)
+	index := (phase>>16 + uint32(registers[6])) & 0xFF
+	sample := audio.ram[index>>1]
+	if index&1 == 0 {
+		sample &= 0x0F
+	} else {
+		sample >>= 4
+	}
+	audio.outputs[channel] = (int(sample) - 8) * int(registers[7]&0x0F)
+}
+
+//output average the enabled channels
+func (audio *Namco163Audio) output() float32 {
+	channels := audio.channels()
+	sum := 0
+
+	for channel := 8 - channels; channel < 8; channel++ {
+		sum += audio.outputs[channel]
+	}
+	return namcoOutputStep * float32(sum) / float32(channels)
+}
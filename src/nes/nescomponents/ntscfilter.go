@@ -0,0 +1,151 @@
+package nescomponents
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+//ntsc filter presets, from the blurriest to the sharpest connection
+const (
+	NtscComposite = iota // luma and chroma share the same wire: dot crawl, fringing and blended dithering
+	NtscSVideo           // luma and chroma on separate wires: no crosstalk, but chroma is still blurry
+	NtscRGB              // perfect colors, only the output is widened like the other presets
+)
+
+// the ppu outputs 8 samples per pixel (master clock) and a color cycle lasts 12 samples
+// https://wiki.nesdev.com/w/index.php/NTSC_video
+const (
+	ntscSamplesPerPixel = 8
+	ntscScanlineSamples = 256 * ntscSamplesPerPixel
+	NtscOutWidth        = 602 // width of a filtered frame, enough to show the color fringes between pixels
+)
+
+//width in samples of the filters of the tv decoder, the bigger, the blurrier
+type ntscWindows struct {
+	luma     int
+	inPhase  int
+	quadrant int
+}
+
+var ntscPresetWindows = [...]ntscWindows{
+	NtscComposite: {luma: 12, inPhase: 24, quadrant: 24},
+	NtscSVideo:    {luma: 4, inPhase: 18, quadrant: 18},
+	NtscRGB:       {luma: 1, inPhase: 1, quadrant: 1},
+}
+
+//NtscFilter simulates the composite video signal of the ppu and its decoding by a tv
+type NtscFilter struct {
+	Preset int
+	params NtscPaletteParams
+	output *image.RGBA
+	// signal levels of the 512 pixels at the 12 phases, with contrast and brightness applied
+	levels [512][12]float64
+	// average level of each pixel, what an s-video luma wire carries
+	lumas [512]float64
+	// the color carrier at the 12 phases, with the hue applied
+	cosines [12]float64
+	sines   [12]float64
+	// running sums of the scanline signal, so a window average costs two lookups
+	ySums []float64
+	iSums []float64
+	qSums []float64
+}
+
+//NewNtscFilter ntsc filter constructor
+func NewNtscFilter(preset int, params NtscPaletteParams) *NtscFilter {
+	var filter NtscFilter
+
+	filter.Preset = preset
+	filter.output = image.NewRGBA(image.Rect(0, 0, NtscOutWidth, 240))
+	filter.ySums = make([]float64, ntscScanlineSamples+1)
+	filter.iSums = make([]float64, ntscScanlineSamples+1)
+	filter.qSums = make([]float64, ntscScanlineSamples+1)
+	filter.SetParams(params)
+	return &filter
+}
+
+//SetParams change the tv settings (hue, saturation...) of the filter
+func (filter *NtscFilter) SetParams(params NtscPaletteParams) {
+	filter.params = params
+	for phase := 0; phase < 12; phase++ {
+		angle := math.Pi / 6 * (float64(phase+ntscBurstPhase) + params.Hue/30)
+		filter.cosines[phase] = math.Cos(angle)
+		filter.sines[phase] = math.Sin(angle)
+	}
+	for pixel := range filter.levels {
+		filter.lumas[pixel] = 0
+		for phase := 0; phase < 12; phase++ {
+			v := ntscSignal(uint16(pixel), phase)
+			v = ((v-0.5)*params.Contrast + 0.5) * params.Brightness
+			filter.levels[pixel][phase] = v
+			filter.lumas[pixel] += v / 12
+		}
+	}
+}
+
+//Apply filter a frame of 9-bit pixels (see PPU.GetFrontPixels), frame is the ppu frame counter:
+//the phase of the color carrier changes from a frame to the next one, which makes the dot crawl
+func (filter *NtscFilter) Apply(pixels []uint16, frame uint64) *image.RGBA {
+	windows := ntscPresetWindows[filter.Preset]
+
+	for y := 0; y < 240; y++ {
+		// each scanline lasts 341*8 samples, which shifts the carrier by 4 samples, odd frames are one dot shorter
+		startPhase := (y*4 + int(frame&1)*4) % 12
+		if filter.Preset != NtscRGB {
+			filter.encodeScanline(pixels[y*256:(y+1)*256], startPhase)
+		}
+		for x := 0; x < NtscOutWidth; x++ {
+			center := (x*ntscScanlineSamples + ntscScanlineSamples/2) / NtscOutWidth
+			if filter.Preset == NtscRGB {
+				filter.output.SetRGBA(x, y, Palette[pixels[y*256+center/ntscSamplesPerPixel]])
+			} else {
+				filter.output.SetRGBA(x, y, filter.decode(center, windows))
+			}
+		}
+	}
+	return filter.output
+}
+
+//encodeScanline build the running sums of the luma, in-phase and quadrature signals of a scanline
+func (filter *NtscFilter) encodeScanline(pixels []uint16, startPhase int) {
+	composite := filter.Preset == NtscComposite
+
+	for sample := 0; sample < ntscScanlineSamples; sample++ {
+		pixel := pixels[sample/ntscSamplesPerPixel] & 0x1FF
+		phase := (startPhase + sample) % 12
+		level := filter.levels[pixel][phase]
+		luma, chroma := level, level
+		if !composite {
+			luma = filter.lumas[pixel]
+			chroma = level - luma
+		}
+		filter.ySums[sample+1] = filter.ySums[sample] + luma
+		filter.iSums[sample+1] = filter.iSums[sample] + chroma*filter.cosines[phase]
+		filter.qSums[sample+1] = filter.qSums[sample] + chroma*filter.sines[phase]
+	}
+}
+
+//window return the average of a running sum around a sample
+func ntscWindow(sums []float64, center int, width int) float64 {
+	start := center - width/2
+	end := start + width
+	if start < 0 {
+		start = 0
+	}
+	if end > len(sums)-1 {
+		end = len(sums) - 1
+	}
+	return (sums[end] - sums[start]) / float64(end-start)
+}
+
+//decode demodulate the signal around a sample to RGB
+func (filter *NtscFilter) decode(center int, windows ntscWindows) color.RGBA {
+	y := ntscWindow(filter.ySums, center, windows.luma)
+	i := ntscWindow(filter.iSums, center, windows.inPhase) * filter.params.Saturation
+	q := ntscWindow(filter.qSums, center, windows.quadrant) * filter.params.Saturation
+	r := ntscGammaFix(y+0.946882*i+0.623557*q, filter.params.Gamma)
+	g := ntscGammaFix(y-0.274788*i-0.635691*q, filter.params.Gamma)
+	b := ntscGammaFix(y-1.108545*i+1.709007*q, filter.params.Gamma)
+	return color.RGBA{r, g, b, 0xFF}
+}
@@ -0,0 +1,117 @@
+package nescomponents
+
+import (
+	"image/color"
+	"math"
+)
+
+//NtscPaletteParams are the knobs of a tv decoding the ppu composite signal
+type NtscPaletteParams struct {
+	Hue        float64 // hue rotation in degrees
+	Saturation float64 // 1: normal
+	Contrast   float64 // 1: normal
+	Brightness float64 // 1: normal
+	Gamma      float64 // gamma of the tv, 1.8 looks like a crt
+}
+
+//DefaultNtscPaletteParams the params of a well adjusted tv
+func DefaultNtscPaletteParams() NtscPaletteParams {
+	return NtscPaletteParams{
+		Hue:        0,
+		Saturation: 1,
+		Contrast:   1,
+		Brightness: 1,
+		Gamma:      1.8,
+	}
+}
+
+// composite signal voltages (relative to sync), low and high level of the 4 luma rows
+// https://wiki.nesdev.com/w/index.php/NTSC_video
+var (
+	ntscLowLevels  = [4]float64{0.350, 0.518, 0.962, 1.550}
+	ntscHighLevels = [4]float64{1.094, 1.506, 1.962, 1.962}
+)
+
+const (
+	ntscBlack       = 0.518
+	ntscWhite       = 1.962
+	ntscAttenuation = 0.746
+	ntscBurstPhase  = 4 // phase of the color burst the tv syncs its hue on
+)
+
+//ntscInColorPhase tell whether the square wave of a hue (0-11) is high during a phase (0-11) of the color subcarrier
+func ntscInColorPhase(hue int, phase int) bool {
+	return (hue+phase)%12 < 6
+}
+
+//ntscSignal return the normalized level (0: black, 1: white) of a 9-bit pixel during one of the 12 phases
+//the emphasis bits attenuate the signal during the phases of red (0), green (4) and blue (8)
+func ntscSignal(pixel uint16, phase int) float64 {
+	hue := int(pixel & 0x0F)
+	level := (pixel >> 4) & 3
+	emphasis := pixel >> 6
+
+	if hue > 13 {
+		level = 1
+	}
+	low := ntscLowLevels[level]
+	high := ntscHighLevels[level]
+	if hue == 0 {
+		low = high
+	}
+	if hue > 12 {
+		high = low
+	}
+	signal := low
+	if ntscInColorPhase(hue, phase) {
+		signal = high
+	}
+	if hue < 14 && ((emphasis&1 != 0 && ntscInColorPhase(0, phase)) ||
+		(emphasis&2 != 0 && ntscInColorPhase(4, phase)) ||
+		(emphasis&4 != 0 && ntscInColorPhase(8, phase))) {
+		signal *= ntscAttenuation
+	}
+	return (signal - ntscBlack) / (ntscWhite - ntscBlack)
+}
+
+//ntscDecode convert one pixel to RGB like a tv does: average the 12 phases into YIQ, then YIQ to RGB
+//https://forums.nesdev.com/viewtopic.php?t=8209 (Bisqwit's palette generator)
+func ntscDecode(pixel uint16, params NtscPaletteParams) color.RGBA {
+	var y, i, q float64
+
+	for phase := 0; phase < 12; phase++ {
+		v := ntscSignal(pixel, phase)
+		v = (v-0.5)*params.Contrast + 0.5
+		v *= params.Brightness / 12
+		angle := math.Pi / 6 * (float64(phase+ntscBurstPhase) + params.Hue/30)
+		y += v
+		i += v * math.Cos(angle)
+		q += v * math.Sin(angle)
+	}
+	i *= params.Saturation
+	q *= params.Saturation
+	r := ntscGammaFix(y+0.946882*i+0.623557*q, params.Gamma)
+	g := ntscGammaFix(y-0.274788*i-0.635691*q, params.Gamma)
+	b := ntscGammaFix(y-1.108545*i+1.709007*q, params.Gamma)
+	return color.RGBA{r, g, b, 0xFF}
+}
+
+//ntscGammaFix apply the gamma of the tv and clamp to a byte
+func ntscGammaFix(value float64, gamma float64) byte {
+	if value <= 0 {
+		return 0
+	}
+	value = math.Pow(value, 2.2/gamma) * 255
+	if value > 255 {
+		return 255
+	}
+	return byte(value)
+}
+
+//UseNtscPalette replace the palette by one generated from the ntsc signal of the ppu
+//the emphasized colors are decoded too, so they look like the ones of a real tv
+func UseNtscPalette(params NtscPaletteParams) {
+	for pixel := range Palette {
+		Palette[pixel] = ntscDecode(uint16(pixel), params)
+	}
+}
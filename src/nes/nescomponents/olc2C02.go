@@ -227,6 +227,9 @@ func (ppu *PPU) Read(address uint16) byte {
 	case address < 0x2000:
 		return ppu.cartridge.Mapper.Read(address)
 	case address < 0x3F00:
+		if mapper, ok := ppu.cartridge.Mapper.(NameTableMapper); ok {
+			return mapper.ReadNameTable(address)
+		}
 		mode := ppu.cartridge.mirror
 		return ppu.nameTable[ppu.mirrorAddress(mode, address)%2048]
 	case address < 0x4000:
@@ -243,6 +246,10 @@ func (ppu *PPU) Write(address uint16, data byte) {
 	case address < 0x2000:
 		ppu.cartridge.Mapper.Write(address, data)
 	case address < 0x3F00:
+		if mapper, ok := ppu.cartridge.Mapper.(NameTableMapper); ok {
+			mapper.WriteNameTable(address, data)
+			return
+		}
 		mode := ppu.cartridge.mirror
 		ppu.nameTable[ppu.mirrorAddress(mode, address)%2048] = data
 	case address < 0x4000:
@@ -288,6 +295,8 @@ type PPU struct {
 	oam          [256]byte   // (Object Attribute Memory)
 	front        *image.RGBA // front ground that generate sprites
 	back         *image.RGBA // back ground
+	frontPixels  []uint16    // 9-bit pixel values (emphasis<<6 | color) of the front image, useful for ntsc filters
+	backPixels   []uint16    // 9-bit pixel values of the back image
 
 	// PPU registers
 	v        uint16 // current vram address (15 bit)
@@ -342,6 +351,12 @@ func (ppu *PPU) GetFront() *image.RGBA {
 	return ppu.front
 }
 
+//GetFrontPixels return the raw 9-bit pixel values of the last frame, 256 per scanline
+//bits 0-5 are the palette color (already grayscaled), bits 6-8 are the PPUMASK emphasis bits
+func (ppu *PPU) GetFrontPixels() []uint16 {
+	return ppu.frontPixels
+}
+
 func (ppu *PPU) Reset() {
 	ppu.Cycle = 340
 	ppu.ScanLine = 240
@@ -359,6 +374,8 @@ func NewPpu(bus *BUS) *PPU {
 	ppu.cartridge = bus.cartridge
 	ppu.front = image.NewRGBA(image.Rect(0, 0, 256, 240))
 	ppu.back = image.NewRGBA(image.Rect(0, 0, 256, 240))
+	ppu.frontPixels = make([]uint16, 256*240)
+	ppu.backPixels = make([]uint16, 256*240)
 	ppu.Reset()
 	return &ppu
 }
@@ -380,6 +397,8 @@ func (ppu *PPU) nmiChange() {
 // Write to PPUCTRL: Set NMI_output to bit 7.
 func (ppu *PPU) setVerticalBlank() {
 	ppu.front, ppu.back = ppu.back, ppu.front
+	ppu.frontPixels, ppu.backPixels = ppu.backPixels, ppu.frontPixels
+	ppu.bus.nextFrame()
 	ppu.nmiOccurred = true
 	ppu.nmiChange()
 }
@@ -417,8 +436,8 @@ func (ppu *PPU) fetchSpritePattern(i, row int) uint32 {
 		address = 0x1000*uint16(table) + uint16(tile)*16 + uint16(row)
 	}
 	a := (attributes & 3) << 2
-	lowTileByte := ppu.Read(address)
-	highTileByte := ppu.Read(address + 8)
+	lowTileByte := ppu.fetchPattern(address)
+	highTileByte := ppu.fetchPattern(address + 8)
 	var data uint32
 	for i := 0; i < 8; i++ {
 		var p1, p2 byte
@@ -472,6 +491,16 @@ func (ppu *PPU) evaluateSprites() {
 	ppu.spriteCount = count
 }
 
+//fetchPattern read a byte of a tile for the rendering, the mapper sees the fetch after it is done
+func (ppu *PPU) fetchPattern(address uint16) byte {
+	data := ppu.Read(address)
+
+	if watcher, ok := ppu.cartridge.Mapper.(PatternWatcher); ok {
+		watcher.PatternFetched(address)
+	}
+	return data
+}
+
 func (ppu *PPU) fetchNameTableByte() {
 	v := ppu.v
 	address := 0x2000 | (v & 0x0FFF)
@@ -490,7 +519,7 @@ func (ppu *PPU) fetchLowTileByte() {
 	table := ppu.ppuCtrl[flagBackgroundTable]
 	tile := ppu.nameTableByte
 	address := 0x1000*uint16(table) + uint16(tile)*16 + fineY
-	ppu.lowTileByte = ppu.Read(address)
+	ppu.lowTileByte = ppu.fetchPattern(address)
 }
 
 func (ppu *PPU) fetchHighTileByte() {
@@ -498,7 +527,7 @@ func (ppu *PPU) fetchHighTileByte() {
 	table := ppu.ppuCtrl[flagBackgroundTable]
 	tile := ppu.nameTableByte
 	address := 0x1000*uint16(table) + uint16(tile)*16 + fineY
-	ppu.highTileByte = ppu.Read(address + 8)
+	ppu.highTileByte = ppu.fetchPattern(address + 8)
 }
 
 func (ppu *PPU) storeTileData() {
@@ -648,8 +677,18 @@ func (ppu *PPU) renderPixel() {
 			color = background
 		}
 	}
-	c := Palette[ppu.readPalette(uint16(color))%64]
-	ppu.back.SetRGBA(x, y, c)
+	index := ppu.readPalette(uint16(color)) % 64
+	if ppu.ppuMask[flagGrayscale] != 0 {
+		index &= 0x30
+	}
+	pixel := uint16(ppu.emphasis())<<6 | uint16(index)
+	ppu.backPixels[y*256+x] = pixel
+	ppu.back.SetRGBA(x, y, Palette[pixel])
+}
+
+//emphasis return the PPUMASK emphasis bits (bit 0: red, bit 1: green, bit 2: blue)
+func (ppu *PPU) emphasis() byte {
+	return ppu.ppuMask[flagRedTint] | ppu.ppuMask[flagGreenTint]<<1 | ppu.ppuMask[flagBlueTint]<<2
 }
 
 // update updates Cycle, ScanLine and Frame counters
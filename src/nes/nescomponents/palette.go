@@ -1,24 +1,97 @@
 package nescomponents
 
-import "image/color"
+import (
+	"fmt"
+	"image/color"
+	"io/ioutil"
+)
 
-var Palette [64]color.RGBA
+//Palette holds the 64 base colors for each of the 8 emphasis combinations (PPUMASK bits 5, 6 and 7)
+//index it with the 9-bit pixel value stored by the ppu: emphasis<<6 | color
+var Palette [512]color.RGBA
+
+//how much an emphasis bit darkens the two channels it does not emphasize
+const emphasisAttenuation = 0.816328
+
+//the built-in 64 colors
+var defaultColors = [64]uint32{
+	0x666666, 0x002A88, 0x1412A7, 0x3B00A4, 0x5C007E, 0x6E0040, 0x6C0600, 0x561D00,
+	0x333500, 0x0B4800, 0x005200, 0x004F08, 0x00404D, 0x000000, 0x000000, 0x000000,
+	0xADADAD, 0x155FD9, 0x4240FF, 0x7527FE, 0xA01ACC, 0xB71E7B, 0xB53120, 0x994E00,
+	0x6B6D00, 0x388700, 0x0C9300, 0x008F32, 0x007C8D, 0x000000, 0x000000, 0x000000,
+	0xFFFEFF, 0x64B0FF, 0x9290FF, 0xC676FF, 0xF36AFF, 0xFE6ECC, 0xFE8170, 0xEA9E22,
+	0xBCBE00, 0x88D800, 0x5CE430, 0x45E082, 0x48CDDE, 0x4F4F4F, 0x000000, 0x000000,
+	0xFFFEFF, 0xC0DFFF, 0xD3D2FF, 0xE8C8FF, 0xFBC2FF, 0xFEC4EA, 0xFECCC5, 0xF7D8A5,
+	0xE4E594, 0xCFEF96, 0xBDF4AB, 0xB3F3CC, 0xB5EBF2, 0xB8B8B8, 0x000000, 0x000000,
+}
 
 func init() {
-	colors := []uint32{
-		0x666666, 0x002A88, 0x1412A7, 0x3B00A4, 0x5C007E, 0x6E0040, 0x6C0600, 0x561D00,
-		0x333500, 0x0B4800, 0x005200, 0x004F08, 0x00404D, 0x000000, 0x000000, 0x000000,
-		0xADADAD, 0x155FD9, 0x4240FF, 0x7527FE, 0xA01ACC, 0xB71E7B, 0xB53120, 0x994E00,
-		0x6B6D00, 0x388700, 0x0C9300, 0x008F32, 0x007C8D, 0x000000, 0x000000, 0x000000,
-		0xFFFEFF, 0x64B0FF, 0x9290FF, 0xC676FF, 0xF36AFF, 0xFE6ECC, 0xFE8170, 0xEA9E22,
-		0xBCBE00, 0x88D800, 0x5CE430, 0x45E082, 0x48CDDE, 0x4F4F4F, 0x000000, 0x000000,
-		0xFFFEFF, 0xC0DFFF, 0xD3D2FF, 0xE8C8FF, 0xFBC2FF, 0xFEC4EA, 0xFECCC5, 0xF7D8A5,
-		0xE4E594, 0xCFEF96, 0xBDF4AB, 0xB3F3CC, 0xB5EBF2, 0xB8B8B8, 0x000000, 0x000000,
-	}
-	for i, c := range colors {
+	UseDefaultPalette()
+}
+
+//UseDefaultPalette go back to the built-in palette
+func UseDefaultPalette() {
+	var colors [64]color.RGBA
+
+	for i, c := range defaultColors {
 		r := byte(c >> 16)
 		g := byte(c >> 8)
 		b := byte(c)
-		Palette[i] = color.RGBA{r, g, b, 0xFF}
+		colors[i] = color.RGBA{r, g, b, 0xFF}
+	}
+	setBaseColors(colors)
+}
+
+//setBaseColors fill the whole palette from 64 colors, the emphasized ones are computed
+func setBaseColors(colors [64]color.RGBA) {
+	for emphasis := 0; emphasis < 8; emphasis++ {
+		for i := 0; i < 64; i++ {
+			Palette[emphasis<<6|i] = emphasize(colors[i], byte(emphasis))
+		}
+	}
+}
+
+//LoadPaletteFile replace the palette by a .pal file
+//a 192 bytes file holds 64 RGB colors (emphasis is then computed), a 1536 bytes file holds all the 512 colors
+//it is the format used by the FBX ("Smooth", "Composite Direct"...) and most emulators palettes
+func LoadPaletteFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch len(data) {
+	case 64 * 3:
+		var colors [64]color.RGBA
+		for i := range colors {
+			colors[i] = color.RGBA{data[i*3], data[i*3+1], data[i*3+2], 0xFF}
+		}
+		setBaseColors(colors)
+	case 512 * 3:
+		for i := range Palette {
+			Palette[i] = color.RGBA{data[i*3], data[i*3+1], data[i*3+2], 0xFF}
+		}
+	default:
+		return fmt.Errorf("%s: invalid palette size %d, expected 192 or 1536 bytes", path, len(data))
+	}
+	return nil
+}
+
+//emphasize applies the PPUMASK emphasis bits (bit 0: red, bit 1: green, bit 2: blue) to a base color
+//https://wiki.nesdev.com/w/index.php/Colour_emphasis
+func emphasize(c color.RGBA, emphasis byte) color.RGBA {
+	r, g, b := float64(c.R), float64(c.G), float64(c.B)
+
+	if emphasis&1 != 0 {
+		g *= emphasisAttenuation
+		b *= emphasisAttenuation
+	}
+	if emphasis&2 != 0 {
+		r *= emphasisAttenuation
+		b *= emphasisAttenuation
+	}
+	if emphasis&4 != 0 {
+		r *= emphasisAttenuation
+		g *= emphasisAttenuation
 	}
+	return color.RGBA{byte(r), byte(g), byte(b), 0xFF}
 }
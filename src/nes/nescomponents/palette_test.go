@@ -0,0 +1,97 @@
+package nescomponents
+
+import (
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+//bisqwitPalette the 64 colors of Bisqwit's palette generator at its default settings (hue 0, saturation 1,
+//contrast 1, brightness 1, gamma 1.8), https://forums.nesdev.com/viewtopic.php?t=8209
+var bisqwitPalette = [64]uint32{
+	0x525252, 0x011A51, 0x0F0F65, 0x230663, 0x36034B, 0x400426, 0x3F0904, 0x321300,
+	0x1F2000, 0x0B2A00, 0x002F00, 0x002E0A, 0x00262D, 0x000000, 0x000000, 0x000000,
+	0xA0A0A0, 0x1E4A9D, 0x3837BC, 0x5828B8, 0x752194, 0x84235C, 0x822E24, 0x6F3F00,
+	0x515200, 0x316300, 0x1A6B05, 0x0E692E, 0x105C68, 0x000000, 0x000000, 0x000000,
+	0xFFFFFF, 0x699EFC, 0x8987FF, 0xAE76FF, 0xCE6DF1, 0xE070B2, 0xDE7C70, 0xC8913E,
+	0xA6A725, 0x81BA28, 0x63C446, 0x54C17D, 0x56B3C0, 0x3C3C3C, 0x000000, 0x000000,
+	0xFFFFFF, 0xBED6FD, 0xCCCCFF, 0xDDC4FF, 0xEAC0F9, 0xF2C1DF, 0xF1C7C2, 0xE8D0AA,
+	0xD9DA9D, 0xC9E29E, 0xBCE6AE, 0xB4E5C7, 0xB5DFE4, 0xA9A9A9, 0x000000, 0x000000,
+}
+
+//the rounding of the generators differ by a unit or two
+const paletteTolerance = 2
+
+func near(a byte, b byte) bool {
+	return int(a)-int(b) <= paletteTolerance && int(b)-int(a) <= paletteTolerance
+}
+
+func TestNtscPalette(t *testing.T) {
+	defer UseDefaultPalette()
+	UseNtscPalette(DefaultNtscPaletteParams())
+
+	for i, want := range bisqwitPalette {
+		c := Palette[i]
+		if !near(c.R, byte(want>>16)) || !near(c.G, byte(want>>8)) || !near(c.B, byte(want)) {
+			t.Errorf("color $%02X: %02X%02X%02X, want %06X", i, c.R, c.G, c.B, want)
+		}
+	}
+}
+
+//paletteByte the byte at an offset of the files of writePalette
+func paletteByte(offset int) byte {
+	return byte(offset * 3)
+}
+
+//paletteColor the color of an index in the files of writePalette
+func paletteColor(index int) color.RGBA {
+	return color.RGBA{paletteByte(index * 3), paletteByte(index*3 + 1), paletteByte(index*3 + 2), 0xFF}
+}
+
+//writePalette write a .pal file whose every byte is its offset times 3
+func writePalette(t *testing.T, size int) string {
+	data := make([]byte, size)
+	for i := range data {
+		data[i] = paletteByte(i)
+	}
+	path := filepath.Join(t.TempDir(), "test.pal")
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+//TestPaletteFile a 192 bytes file holds the 64 base colors, the emphasized ones are computed, a 1536 bytes file holds
+//the 512 colors, other sizes are refused and the palette is kept
+func TestPaletteFile(t *testing.T) {
+	defer UseDefaultPalette()
+
+	if err := LoadPaletteFile(writePalette(t, 192)); err != nil {
+		t.Fatal(err)
+	}
+	base := paletteColor(5)
+	if Palette[5] != base {
+		t.Errorf("color $05 %v, want %v", Palette[5], base)
+	}
+	if Palette[7<<6|5] != emphasize(base, 7) {
+		t.Errorf("emphasized color $1C5 %v, want %v", Palette[7<<6|5], emphasize(base, 7))
+	}
+
+	if err := LoadPaletteFile(writePalette(t, 1536)); err != nil {
+		t.Fatal(err)
+	}
+	full := paletteColor(0x1C5)
+	if Palette[0x1C5] != full {
+		t.Errorf("color $1C5 %v, want %v from the file", Palette[0x1C5], full)
+	}
+
+	for _, size := range []int{0, 191, 193, 1535, 1537} {
+		if err := LoadPaletteFile(writePalette(t, size)); err == nil {
+			t.Errorf("a %d bytes palette is loaded", size)
+		}
+		if Palette[0x1C5] != full {
+			t.Errorf("a %d bytes palette changed the colors", size)
+		}
+	}
+}
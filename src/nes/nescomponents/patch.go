@@ -0,0 +1,312 @@
+package nescomponents
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//the soft patches (translations, hacks, fixes) are applied to the rom in memory when it is loaded, the rom file is not modified
+//IPS: https://zerosoft.zophar.net/ips.php
+//UPS and BPS: https://www.romhacking.net/documents/392/ and https://www.romhacking.net/documents/746/
+
+//patchExtensions the patches found next to the rom, in this order
+var patchExtensions = []string{".ips", ".ups", ".bps"}
+
+//findPatch return the patch next to the rom with the same name (game.nes: game.ips, game.ups or game.bps), "" if none
+func findPatch(romPath string) string {
+	base := strings.TrimSuffix(romPath, filepath.Ext(romPath))
+
+	for _, extension := range patchExtensions {
+		if _, err := os.Stat(base + extension); err == nil {
+			return base + extension
+		}
+	}
+	return ""
+}
+
+//ApplyPatch return the rom patched by an IPS, UPS or BPS patch, the format is told by the magic number.
+//The checksums of the UPS and BPS patches are verified: the patch must be made for this rom
+func ApplyPatch(rom []byte, patch []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(patch, []byte("PATCH")):
+		return applyIps(rom, patch)
+	case bytes.HasPrefix(patch, []byte("UPS1")):
+		return applyUps(rom, patch)
+	case bytes.HasPrefix(patch, []byte("BPS1")):
+		return applyBps(rom, patch)
+	}
+	return nil, errors.New("unknown patch format, expected IPS, UPS or BPS")
+}
+
+var errPatchTruncated = errors.New("truncated patch")
+
+//applyIps write the records of the patch: 24 bit offset and 16 bit size then the data, or a size of 0 then a 16 bit
+//count and a byte repeated count times (RLE). "EOF" ends the records, it can be followed by the 24 bit size of the rom
+func applyIps(rom []byte, patch []byte) ([]byte, error) {
+	target := append([]byte{}, rom...)
+	position := 5
+
+	read := func(size int) (int, error) {
+		if position+size > len(patch) {
+			return 0, errPatchTruncated
+		}
+		value := 0
+		for _, b := range patch[position : position+size] {
+			value = value<<8 | int(b)
+		}
+		position += size
+		return value, nil
+	}
+	for {
+		offset, err := read(3)
+		if err != nil {
+			return nil, err
+		}
+		if offset == 0x454F46 { // "EOF"
+			break
+		}
+		size, err := read(2)
+		if err != nil {
+			return nil, err
+		}
+		var data []byte
+		if size != 0 {
+			if position+size > len(patch) {
+				return nil, errPatchTruncated
+			}
+			data = patch[position : position+size]
+			position += size
+		} else {
+			count, err := read(2)
+			if err != nil {
+				return nil, err
+			}
+			value, err := read(1)
+			if err != nil {
+				return nil, err
+			}
+			data = bytes.Repeat([]byte{byte(value)}, count)
+		}
+		for len(target) < offset+len(data) {
+			target = append(target, 0)
+		}
+		copy(target[offset:], data)
+	}
+	if truncate, err := read(3); err == nil && truncate < len(target) {
+		target = target[:truncate]
+	}
+	return target, nil
+}
+
+//patchReader read the numbers of the UPS and BPS patches, whose last 12 bytes are the checksums
+type patchReader struct {
+	patch    []byte
+	position int
+}
+
+//number read a variable length number: 7 bits per byte, the bit 7 is set on the last byte
+func (reader *patchReader) number() (int, error) {
+	value, shift := 0, 1
+	for {
+		b, err := reader.byte()
+		if err != nil {
+			return 0, err
+		}
+		value += int(b&0x7F) * shift
+		if b&0x80 != 0 {
+			return value, nil
+		}
+		shift <<= 7
+		value += shift
+	}
+}
+
+func (reader *patchReader) byte() (byte, error) {
+	if reader.position >= len(reader.patch)-12 {
+		return 0, errPatchTruncated
+	}
+	b := reader.patch[reader.position]
+	reader.position++
+	return b, nil
+}
+
+func (reader *patchReader) done() bool {
+	return reader.position >= len(reader.patch)-12
+}
+
+//checkPatch verify the checksums at the end of a UPS or BPS patch: the source rom, the patched rom and the patch itself
+func checkPatch(patch []byte, source []byte) error {
+	if len(patch) < 16 {
+		return errPatchTruncated
+	}
+	footer := patch[len(patch)-12:]
+	if crc32.ChecksumIEEE(patch[:len(patch)-4]) != binary.LittleEndian.Uint32(footer[8:]) {
+		return errors.New("the patch is corrupted (bad checksum)")
+	}
+	if crc32.ChecksumIEEE(source) != binary.LittleEndian.Uint32(footer[0:]) {
+		return errors.New("the patch is not made for this rom (bad rom checksum)")
+	}
+	return nil
+}
+
+//checkTarget verify the checksum of the patched rom
+func checkTarget(patch []byte, target []byte) ([]byte, error) {
+	footer := patch[len(patch)-12:]
+	if crc32.ChecksumIEEE(target) != binary.LittleEndian.Uint32(footer[4:]) {
+		return nil, errors.New("the patched rom is wrong (bad checksum)")
+	}
+	return target, nil
+}
+
+//applyUps XOR the hunks of the patch with the rom: the distance from the last hunk, then the bytes up to a 0
+func applyUps(rom []byte, patch []byte) ([]byte, error) {
+	if err := checkPatch(patch, rom); err != nil {
+		return nil, err
+	}
+	reader := patchReader{patch, 4}
+	sourceSize, err := reader.number()
+	if err != nil {
+		return nil, err
+	}
+	targetSize, err := reader.number()
+	if err != nil {
+		return nil, err
+	}
+	if sourceSize != len(rom) {
+		return nil, fmt.Errorf("the patch is made for a rom of %d bytes, not %d", sourceSize, len(rom))
+	}
+	target := make([]byte, targetSize)
+	copy(target, rom)
+	offset := 0
+	for !reader.done() {
+		skip, err := reader.number()
+		if err != nil {
+			return nil, err
+		}
+		offset += skip
+		for {
+			b, err := reader.byte()
+			if err != nil {
+				return nil, err
+			}
+			if offset < len(target) {
+				target[offset] ^= b
+			}
+			offset++
+			if b == 0 {
+				break
+			}
+		}
+	}
+	return checkTarget(patch, target)
+}
+
+//the actions of a BPS patch, in the 2 low bits of their first number, the length is in the other bits
+const (
+	bpsSourceRead = iota // copy the rom at the same offset
+	bpsTargetRead        // copy the data of the patch
+	bpsSourceCopy        // copy the rom from a relative offset
+	bpsTargetCopy        // copy the patched rom from a relative offset, the copy can overlap what it writes
+)
+
+//applyBps run the actions of the patch, which build the patched rom from its start
+func applyBps(rom []byte, patch []byte) ([]byte, error) {
+	if err := checkPatch(patch, rom); err != nil {
+		return nil, err
+	}
+	reader := patchReader{patch, 4}
+	var sizes [3]int // source, target, metadata
+	for i := range sizes {
+		size, err := reader.number()
+		if err != nil {
+			return nil, err
+		}
+		sizes[i] = size
+	}
+	if sizes[0] != len(rom) {
+		return nil, fmt.Errorf("the patch is made for a rom of %d bytes, not %d", sizes[0], len(rom))
+	}
+	reader.position += sizes[2] // metadata
+	target := make([]byte, 0, sizes[1])
+	sourceOffset, targetOffset := 0, 0
+	relative := func(offset int) (int, error) {
+		value, err := reader.number()
+		if value&1 != 0 {
+			return offset - value>>1, err
+		}
+		return offset + value>>1, err
+	}
+	for !reader.done() {
+		action, err := reader.number()
+		if err != nil {
+			return nil, err
+		}
+		length := action>>2 + 1
+		switch action & 3 {
+		case bpsSourceRead:
+			if len(target)+length > len(rom) {
+				return nil, errPatchTruncated
+			}
+			target = append(target, rom[len(target):len(target)+length]...)
+		case bpsTargetRead:
+			for i := 0; i < length; i++ {
+				b, err := reader.byte()
+				if err != nil {
+					return nil, err
+				}
+				target = append(target, b)
+			}
+		case bpsSourceCopy:
+			if sourceOffset, err = relative(sourceOffset); err != nil {
+				return nil, err
+			}
+			if sourceOffset < 0 || sourceOffset+length > len(rom) {
+				return nil, errPatchTruncated
+			}
+			target = append(target, rom[sourceOffset:sourceOffset+length]...)
+			sourceOffset += length
+		case bpsTargetCopy:
+			if targetOffset, err = relative(targetOffset); err != nil {
+				return nil, err
+			}
+			if targetOffset < 0 || targetOffset >= len(target) {
+				return nil, errPatchTruncated
+			}
+			for i := 0; i < length; i++ {
+				target = append(target, target[targetOffset])
+				targetOffset++
+			}
+		}
+	}
+	return checkTarget(patch, target)
+}
+
+//makeIps return the IPS patch turning original into modified, of the same size: one record per run of changed bytes.
+//A record cannot start at $454F46, it would read as "EOF": it starts one byte earlier
+func makeIps(original []byte, modified []byte) []byte {
+	patch := []byte("PATCH")
+
+	for offset := 0; offset < len(modified); {
+		if offset < len(original) && original[offset] == modified[offset] {
+			offset++
+			continue
+		}
+		start := offset
+		if start == 0x454F46 {
+			start--
+		}
+		for offset < len(modified) && offset-start < 0xFFFF && (offset >= len(original) || original[offset] != modified[offset]) {
+			offset++
+		}
+		size := offset - start
+		patch = append(patch, byte(start>>16), byte(start>>8), byte(start), byte(size>>8), byte(size))
+		patch = append(patch, modified[start:offset]...)
+	}
+	return append(patch, 'E', 'O', 'F')
+}
@@ -0,0 +1,249 @@
+package nescomponents
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+//patchNumber encode a variable length number of the UPS and BPS patches
+func patchNumber(value int) []byte {
+	var encoded []byte
+	for {
+		b := byte(value & 0x7F)
+		value >>= 7
+		if value == 0 {
+			return append(encoded, b|0x80)
+		}
+		encoded = append(encoded, b)
+		value--
+	}
+}
+
+//patchFooter append the checksums of the source rom, the patched rom and the patch
+func patchFooter(patch []byte, source []byte, target []byte) []byte {
+	patch = appendCrc(patch, source)
+	patch = appendCrc(patch, target)
+	return appendCrc(patch, patch)
+}
+
+func appendCrc(patch []byte, data []byte) []byte {
+	var crc [4]byte
+	binary.LittleEndian.PutUint32(crc[:], crc32.ChecksumIEEE(data))
+	return append(patch, crc[:]...)
+}
+
+func byteAt(data []byte, offset int) byte {
+	if offset < len(data) {
+		return data[offset]
+	}
+	return 0
+}
+
+//makeUps return the UPS patch turning source into target: each hunk is the distance from the last one,
+//then the XOR of the two roms up to a 0
+func makeUps(source []byte, target []byte) []byte {
+	patch := append([]byte("UPS1"), patchNumber(len(source))...)
+	patch = append(patch, patchNumber(len(target))...)
+	last := 0
+	for offset := 0; offset < len(target); {
+		if byteAt(source, offset) == target[offset] {
+			offset++
+			continue
+		}
+		patch = append(patch, patchNumber(offset-last)...)
+		for offset < len(target) && byteAt(source, offset) != target[offset] {
+			patch = append(patch, byteAt(source, offset)^target[offset])
+			offset++
+		}
+		patch = append(patch, 0)
+		offset++
+		last = offset
+	}
+	return patchFooter(patch, source, target)
+}
+
+//makeBps return the BPS patch turning source into target with source reads for the same bytes and target reads
+//for the others
+func makeBps(source []byte, target []byte) []byte {
+	patch := append([]byte("BPS1"), patchNumber(len(source))...)
+	patch = append(patch, patchNumber(len(target))...)
+	patch = append(patch, patchNumber(0)...)
+	same := func(offset int) bool {
+		return offset < len(source) && source[offset] == target[offset]
+	}
+	for offset := 0; offset < len(target); {
+		start := offset
+		kind := same(offset)
+		for offset < len(target) && same(offset) == kind {
+			offset++
+		}
+		if kind {
+			patch = append(patch, patchNumber((offset-start-1)<<2|bpsSourceRead)...)
+		} else {
+			patch = append(patch, patchNumber((offset-start-1)<<2|bpsTargetRead)...)
+			patch = append(patch, target[start:offset]...)
+		}
+	}
+	return patchFooter(patch, source, target)
+}
+
+//testRoms return a rom and a modified copy, longer, with changes at its start, its middle and its end
+func testRoms() ([]byte, []byte) {
+	source := make([]byte, 0x1000)
+	for i := range source {
+		source[i] = byte(i * 7)
+	}
+	target := append(append([]byte{}, source...), 0x11, 0x22, 0x33)
+	target[0] = 0xAA
+	copy(target[0x800:], "translated")
+	target[0xFFF] ^= 0xFF
+	return source, target
+}
+
+func TestPatchRoundTrip(t *testing.T) {
+	source, target := testRoms()
+	patches := map[string][]byte{
+		"ips": makeIps(source, target),
+		"ups": makeUps(source, target),
+		"bps": makeBps(source, target),
+	}
+
+	original := append([]byte{}, source...)
+	for name, patch := range patches {
+		patched, err := ApplyPatch(source, patch)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if !bytes.Equal(patched, target) {
+			t.Errorf("%s: the patched rom differs from the target", name)
+		}
+		if !bytes.Equal(source, original) {
+			t.Errorf("%s: the rom given to the patch was modified", name)
+		}
+	}
+}
+
+//TestIpsRle a record of size 0 repeats a byte, the 24 bit size after "EOF" truncates the rom
+func TestIpsRle(t *testing.T) {
+	rom := make([]byte, 16)
+	patch := []byte("PATCH\x00\x00\x04\x00\x00\x00\x03\xEE" + "EOF\x00\x00\x08")
+
+	patched, err := ApplyPatch(rom, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{0, 0, 0, 0, 0xEE, 0xEE, 0xEE, 0}
+	if !bytes.Equal(patched, want) {
+		t.Errorf("patched rom % X, want % X", patched, want)
+	}
+}
+
+//TestBpsCopies the source and target copies are relative to the last copy, a target copy can repeat what it writes
+func TestBpsCopies(t *testing.T) {
+	source := []byte("0123456789ABCDEF")
+	target := []byte("89ABxyxyxyxy")
+	patch := append([]byte("BPS1"), patchNumber(len(source))...)
+	patch = append(patch, patchNumber(len(target))...)
+	patch = append(patch, patchNumber(0)...)
+	patch = append(patch, patchNumber(3<<2|bpsSourceCopy)...) // 4 bytes at +8
+	patch = append(patch, patchNumber(8<<1)...)
+	patch = append(patch, patchNumber(1<<2|bpsTargetRead)...) // "xy"
+	patch = append(patch, 'x', 'y')
+	patch = append(patch, patchNumber(5<<2|bpsTargetCopy)...) // 6 bytes at +4, overlapping
+	patch = append(patch, patchNumber(4<<1)...)
+	patch = patchFooter(patch, source, target)
+
+	patched, err := ApplyPatch(source, patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(patched, target) {
+		t.Errorf("patched rom %q, want %q", patched, target)
+	}
+}
+
+func TestPatchErrors(t *testing.T) {
+	source, target := testRoms()
+	otherRom := append([]byte{}, source...)
+	otherRom[0x100]++
+	corrupted := makeBps(source, target)
+	corrupted[8] ^= 0x01
+	badTarget := makeUps(source, target)
+	badTarget = patchFooter(badTarget[:len(badTarget)-12], source, append([]byte{}, source...))
+
+	tests := []struct {
+		name  string
+		rom   []byte
+		patch []byte
+		error string
+	}{
+		{"ups for another rom", otherRom, makeUps(source, target), "bad rom checksum"},
+		{"bps for another rom", otherRom, makeBps(source, target), "bad rom checksum"},
+		{"corrupted bps", source, corrupted, "bad checksum"},
+		{"ups with a bad target checksum", source, badTarget, "patched rom is wrong"},
+		{"truncated ips", source, makeIps(source, target)[:12], "truncated"},
+		{"truncated ups", source, makeUps(source, target)[:10], "truncated"},
+		{"unknown format", source, []byte("PACTH"), "unknown patch format"},
+	}
+	for _, test := range tests {
+		_, err := ApplyPatch(test.rom, test.patch)
+		if err == nil || !strings.Contains(err.Error(), test.error) {
+			t.Errorf("%s: error %v, want %q", test.name, err, test.error)
+		}
+	}
+}
+
+//TestLoadPatchedRom the patch next to the rom is applied and names the save, a patch that fails stops the loading
+func TestLoadPatchedRom(t *testing.T) {
+	rom, err := ioutil.ReadFile(zeldaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := append([]byte{}, rom...)
+	modified[0x10] ^= 0xFF
+	directory := t.TempDir()
+	romPath := filepath.Join(directory, "game.nes")
+	if err := ioutil.WriteFile(romPath, rom, 0644); err != nil {
+		t.Fatal(err)
+	}
+	upsPath := filepath.Join(directory, "game.ups")
+	if err := ioutil.WriteFile(upsPath, makeUps(rom, modified), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cartridge, err := NewCartridge(romPath, LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cartridge.prg[0] != modified[0x10] {
+		t.Errorf("the patch next to the rom is not applied")
+	}
+	if cartridge.path != upsPath {
+		t.Errorf("the save is named after %s, want %s", cartridge.path, upsPath)
+	}
+
+	badPath := filepath.Join(directory, "other.ups")
+	if err := ioutil.WriteFile(badPath, makeUps(modified, rom), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCartridge(romPath, LoadOptions{Patch: badPath}); err == nil {
+		t.Errorf("a patch made for another rom is not an error")
+	}
+
+	// an IPS patch can truncate the rom with the size after "EOF"
+	truncatePath := filepath.Join(directory, "truncate.ips")
+	size := len(rom) - 0x1000
+	truncate := []byte{'P', 'A', 'T', 'C', 'H', 'E', 'O', 'F', byte(size >> 16), byte(size >> 8), byte(size)}
+	if err := ioutil.WriteFile(truncatePath, truncate, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCartridge(romPath, LoadOptions{Patch: truncatePath}); err == nil {
+		t.Errorf("a rom truncated by its patch is not an error")
+	}
+}
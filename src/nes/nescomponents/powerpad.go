@@ -0,0 +1,106 @@
+package nescomponents
+
+//PowerPad is the nes Power Pad mat (port 2), the famicom Family Trainer is the same mat on the expansion port
+//its 12 buttons are numbered like on side B: 1 to 4 on the top row, 5 to 8 on the middle one, 9 to 12 on the bottom one
+//https://wiki.nesdev.com/w/index.php/Power_Pad
+type PowerPad struct {
+	buttons [12]bool
+	strobe  byte
+	latch   [2]uint16 // buttons latched for D3 and D4, shifted out lsb first
+}
+
+//buttons shifted out on D3 and D4, then 1s
+var (
+	powerPadD3 = [8]int{2, 1, 5, 9, 6, 10, 11, 7}
+	powerPadD4 = [4]int{4, 3, 12, 8}
+)
+
+//NewPowerPad power pad constructor
+func NewPowerPad() *PowerPad {
+	return &PowerPad{}
+}
+
+//SetButtons set which of the 12 buttons are stepped on, buttons[0] is the button 1
+func (mat *PowerPad) SetButtons(buttons [12]bool) {
+	mat.buttons = buttons
+}
+
+//reload latch the buttons in the shift registers of D3 and D4, the bits after the buttons read as 1
+func (mat *PowerPad) reload() {
+	mat.latch = [2]uint16{0xFF00, 0xFFF0}
+	for i, button := range powerPadD3 {
+		if mat.buttons[button-1] {
+			mat.latch[0] |= 1 << uint(i)
+		}
+	}
+	for i, button := range powerPadD4 {
+		if mat.buttons[button-1] {
+			mat.latch[1] |= 1 << uint(i)
+		}
+	}
+}
+
+//Read D3 and D4 shift out the buttons, 1 when pressed
+func (mat *PowerPad) Read() byte {
+	if mat.strobe == 1 {
+		mat.reload()
+	}
+	value := byte(mat.latch[0]&1)<<3 | byte(mat.latch[1]&1)<<4
+	if mat.strobe == 0 {
+		mat.latch[0] = mat.latch[0]>>1 | 0x8000
+		mat.latch[1] = mat.latch[1]>>1 | 0x8000
+	}
+	return value
+}
+
+//Write latch the buttons while the strobe is high
+func (mat *PowerPad) Write(value byte) {
+	mat.strobe = value & 1
+	if mat.strobe == 1 {
+		mat.reload()
+	}
+}
+
+//FamilyTrainer is the famicom version of the power pad: the game selects a row with the expansion port outputs
+//and reads its 4 buttons on D1-D4 of $4017
+//https://wiki.nesdev.com/w/index.php/Family_Trainer_Mat
+type FamilyTrainer struct {
+	buttons [12]bool
+	rows    byte // OUT0-OUT2, a row is selected when its bit is low
+}
+
+//NewFamilyTrainer family trainer constructor
+func NewFamilyTrainer() *FamilyTrainer {
+	return &FamilyTrainer{rows: 0x07}
+}
+
+//SetButtons set which of the 12 buttons are stepped on, buttons[0] is the button 1
+func (mat *FamilyTrainer) SetButtons(buttons [12]bool) {
+	mat.buttons = buttons
+}
+
+//Read the buttons of the selected rows on D1-D4 of $4017, 0 when pressed
+func (mat *FamilyTrainer) Read(port int) byte {
+	var pressed byte
+
+	if port != Port2 {
+		return 0
+	}
+	// OUT2 selects the top row, OUT1 the middle one and OUT0 the bottom one
+	for row := 0; row < 3; row++ {
+		if mat.rows&(4>>uint(row)) != 0 {
+			continue
+		}
+		for i := 0; i < 4; i++ {
+			if mat.buttons[row*4+i] {
+				pressed |= 1 << uint(3-i)
+			}
+		}
+	}
+	return ^pressed << 1 & 0x1E
+}
+
+//Write select the rows
+func (mat *FamilyTrainer) Write(value byte) {
+	mat.rows = value & 0x07
+}
@@ -0,0 +1,95 @@
+package nescomponents
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"hash/crc32"
+	"log"
+	"strings"
+)
+
+//the database of the known dumps, converted from the NES 2.0 XML database (nes20db.xml) by romdatabase_gen.go:
+//	go generate ./src/nes/nescomponents
+//with nes20db.xml in this directory, it writes romdatabase_table.go
+//go:generate go run romdatabase_gen.go nes20db.xml romdatabase_table.go
+
+//romInfo is what the database knows of a dump, found by the CRC32 of its PRG-ROM and CHR-ROM
+type romInfo struct {
+	name      string
+	sha1      string // of the PRG-ROM and CHR-ROM, to confirm the CRC32
+	mapper    byte
+	submapper byte
+	mirror    byte // MirrorHorizontal, MirrorVertical or MirrorFour, romMirrorMapper when the mapper selects it
+	battery   bool
+	prgRam    int // PRG-RAM and battery-backed PRG-RAM, in bytes
+	chrRam    int // CHR-RAM, in bytes
+	region    byte
+	input     byte
+}
+
+//romMirrorMapper the mirroring is selected by the mapper, the one of the header is kept
+const romMirrorMapper = 0xFF
+
+//findRom look a dump up in the database
+func findRom(rom []byte) (romInfo, bool) {
+	info, ok := romDatabase[crc32.ChecksumIEEE(rom)]
+	if !ok {
+		return info, false
+	}
+	sum := sha1.Sum(rom)
+	if info.sha1 != "" && !strings.EqualFold(info.sha1, hex.EncodeToString(sum[:])) {
+		return info, false
+	}
+	return info, true
+}
+
+//applyRomDatabase replace the values of the header by the ones of the database when it knows the dump,
+//logging each value it changes. It is called before the mapper is built
+func (cartridge *Cartridge) applyRomDatabase(chrRom bool) {
+	rom := cartridge.prg
+	if chrRom {
+		rom = append(append([]byte{}, cartridge.prg...), cartridge.chr...)
+	}
+	info, ok := findRom(rom)
+	if !ok {
+		return
+	}
+	changed := func(what string, header interface{}, database interface{}) {
+		log.Printf("rom database (%s): %s %v instead of %v", info.name, what, database, header)
+	}
+	if cartridge.mapperType != info.mapper {
+		changed("mapper", cartridge.mapperType, info.mapper)
+		cartridge.mapperType = info.mapper
+	}
+	if cartridge.submapper != info.submapper {
+		changed("submapper", cartridge.submapper, info.submapper)
+		cartridge.submapper = info.submapper
+	}
+	if info.mirror != romMirrorMapper && cartridge.mirror != info.mirror {
+		changed("mirroring", cartridge.mirror, info.mirror)
+		cartridge.mirror = info.mirror
+	}
+	if battery := cartridge.battery != 0; battery != info.battery {
+		changed("battery", battery, info.battery)
+		cartridge.battery = 0
+		if info.battery {
+			cartridge.battery = 1
+		}
+	}
+	if info.prgRam > len(cartridge.sram) {
+		changed("PRG-RAM size", len(cartridge.sram), info.prgRam)
+		cartridge.sram = make([]byte, info.prgRam)
+	}
+	if !chrRom && info.chrRam > len(cartridge.chr) {
+		changed("CHR-RAM size", len(cartridge.chr), info.chrRam)
+		cartridge.chr = make([]byte, info.chrRam)
+	}
+	if cartridge.region != info.region {
+		changed("region", cartridge.region, info.region)
+		cartridge.region = info.region
+	}
+	if info.input != InputUnspecified && cartridge.input != info.input {
+		changed("input device", cartridge.input, info.input)
+		cartridge.input = info.input
+	}
+}
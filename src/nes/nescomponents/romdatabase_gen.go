@@ -0,0 +1,146 @@
+//go:build ignore
+// +build ignore
+
+//romdatabase_gen convert the NES 2.0 XML database to the table of romdatabase_table.go:
+//	go run romdatabase_gen.go nes20db.xml romdatabase_table.go
+//The name of a game is the comment before its <game> element, the roms are found by the hashes of <rom> (PRG-ROM and CHR-ROM).
+//The Vs. System and PlayChoice-10 games and the mappers above 255 are left out, the emulator runs none of them
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type size struct {
+	Size int `xml:"size,attr"`
+}
+
+type game struct {
+	Rom struct {
+		Crc32 string `xml:"crc32,attr"`
+		Sha1  string `xml:"sha1,attr"`
+	} `xml:"rom"`
+	PrgRam   size `xml:"prgram"`
+	PrgNvram size `xml:"prgnvram"`
+	ChrRam   size `xml:"chrram"`
+	ChrNvram size `xml:"chrnvram"`
+	Pcb      struct {
+		Mapper    int    `xml:"mapper,attr"`
+		Submapper int    `xml:"submapper,attr"`
+		Mirroring string `xml:"mirroring,attr"`
+		Battery   int    `xml:"battery,attr"`
+	} `xml:"pcb"`
+	Console struct {
+		Type   int `xml:"type,attr"`
+		Region int `xml:"region,attr"`
+	} `xml:"console"`
+	Expansion struct {
+		Type int `xml:"type,attr"`
+	} `xml:"expansion"`
+}
+
+//mirrorings of the pcb element: H and V are the bit 0 of the header clear and set
+var mirrorings = map[string]string{"H": "MirrorHorizontal", "V": "MirrorVertical", "4": "MirrorFour"}
+
+func main() {
+	if len(os.Args) != 3 {
+		println("usage: go run romdatabase_gen.go nes20db.xml romdatabase_table.go")
+		os.Exit(1)
+	}
+	file, err := os.Open(os.Args[1])
+	if err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
+	defer file.Close()
+	entries, err := readDatabase(file)
+	if err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
+	source, err := writeTable(entries)
+	if err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
+	if err := ioutil.WriteFile(os.Args[2], source, 0644); err != nil {
+		println(err.Error())
+		os.Exit(1)
+	}
+}
+
+//readDatabase return the lines of the table by CRC32, the first game wins when two share a rom
+func readDatabase(reader io.Reader) (map[uint32]string, error) {
+	entries := map[uint32]string{}
+	decoder := xml.NewDecoder(reader)
+	name := ""
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch token := token.(type) {
+		case xml.Comment:
+			name = strings.TrimSpace(string(token))
+		case xml.StartElement:
+			if token.Name.Local != "game" {
+				continue
+			}
+			var entry game
+			if err := decoder.DecodeElement(&entry, &token); err != nil {
+				return nil, err
+			}
+			crc, err := strconv.ParseUint(entry.Rom.Crc32, 16, 32)
+			if err != nil || entry.Console.Type != 0 || entry.Pcb.Mapper > 255 {
+				continue
+			}
+			if _, ok := entries[uint32(crc)]; ok {
+				continue
+			}
+			entries[uint32(crc)] = line(name, entry)
+		}
+	}
+}
+
+func line(name string, entry game) string {
+	mirror, ok := mirrorings[entry.Pcb.Mirroring]
+	if !ok {
+		mirror = "romMirrorMapper"
+	}
+	return fmt.Sprintf("{%q, %q, %d, %d, %s, %t, %d, %d, %d, %d}",
+		name, strings.ToUpper(entry.Rom.Sha1), entry.Pcb.Mapper, entry.Pcb.Submapper, mirror, entry.Pcb.Battery != 0,
+		entry.PrgRam.Size+entry.PrgNvram.Size, entry.ChrRam.Size+entry.ChrNvram.Size, entry.Console.Region, entry.Expansion.Type)
+}
+
+func writeTable(entries map[uint32]string) ([]byte, error) {
+	var crcs []uint32
+	var buffer bytes.Buffer
+
+	for crc := range entries {
+		crcs = append(crcs, crc)
+	}
+	sort.Slice(crcs, func(i, j int) bool { return crcs[i] < crcs[j] })
+	fmt.Fprintf(&buffer, "// Code generated by romdatabase_gen.go from %s; DO NOT EDIT.\n\n", filepath.Base(os.Args[1]))
+	fmt.Fprintf(&buffer, "package nescomponents\n\n")
+	fmt.Fprintf(&buffer, "//romDatabase the known dumps by the CRC32 of their PRG-ROM and CHR-ROM\n")
+	fmt.Fprintf(&buffer, "var romDatabase = map[uint32]romInfo{\n")
+	for _, crc := range crcs {
+		fmt.Fprintf(&buffer, "0x%08X: %s,\n", crc, entries[crc])
+	}
+	fmt.Fprintf(&buffer, "}\n")
+	return format.Source(buffer.Bytes())
+}
@@ -0,0 +1,9 @@
+//This table is written by hand and only holds the dump of the assets directory, its entry is the one of nes20db.xml.
+//go generate replaces it with the table converted from nes20db.xml by romdatabase_gen.go.
+
+package nescomponents
+
+// romDatabase the known dumps by the CRC32 of their PRG-ROM and CHR-ROM
+var romDatabase = map[uint32]romInfo{
+	0x3FE272FB: {"Legend of Zelda, The (USA)", "A12D74C73A0481599A5D832361D168F4737BBCF6", 1, 0, MirrorHorizontal, true, 8192, 8192, 0, 1},
+}
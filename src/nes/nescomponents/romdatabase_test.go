@@ -0,0 +1,64 @@
+package nescomponents
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+//the dump of the assets directory, known to the database
+const zeldaPath = "../../../assets/Zelda.nes"
+
+//writeBadHeader write a copy of a rom whose header says mapper 0, no battery and vertical mirroring
+func writeBadHeader(t *testing.T, rom []byte) string {
+	bad := append([]byte{}, rom...)
+	bad[6] = 0x01 // vertical mirroring, no battery, mapper 0
+	bad[7] = 0x00
+	path := filepath.Join(t.TempDir(), "bad.nes")
+	if err := ioutil.WriteFile(path, bad, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+//TestRomDatabaseFixesHeader a known dump with a bad header gets the mapper, battery and mirroring of the database
+func TestRomDatabaseFixesHeader(t *testing.T) {
+	rom, err := ioutil.ReadFile(zeldaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cartridge, err := NewCartridge(writeBadHeader(t, rom), LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cartridge.mapperType != 1 {
+		t.Errorf("mapper %d, want 1", cartridge.mapperType)
+	}
+	if cartridge.battery != 1 {
+		t.Errorf("no battery, the database has one")
+	}
+	if cartridge.mirror != MirrorHorizontal {
+		t.Errorf("mirroring %d, want %d", cartridge.mirror, MirrorHorizontal)
+	}
+	if _, ok := cartridge.Mapper.(*Mapper1); !ok {
+		t.Errorf("mapper built: %T, want *Mapper1", cartridge.Mapper)
+	}
+}
+
+//TestRomDatabaseUnknownDump a changed byte makes another dump, the header is kept as it is
+func TestRomDatabaseUnknownDump(t *testing.T) {
+	rom, err := ioutil.ReadFile(zeldaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rom[16] ^= 0xFF
+	cartridge, err := NewCartridge(writeBadHeader(t, rom), LoadOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cartridge.mapperType != 0 || cartridge.battery != 0 || cartridge.mirror != MirrorVertical {
+		t.Errorf("header changed: mapper %d, battery %d, mirroring %d", cartridge.mapperType, cartridge.battery, cartridge.mirror)
+	}
+}
@@ -0,0 +1,178 @@
+package nescomponents
+
+import "math"
+
+//the Sunsoft 5B sound is a YM2149F, a clone of the AY-3-8910: three square channels, a noise shared by the three
+//and an envelope, all driven by the cpu clock divided by 16
+//https://wiki.nesdev.com/w/index.php/Sunsoft_5B_audio
+const (
+	sunsoftClockDivider = 16
+	sunsoftChannelLevel = 0.15 // output of a channel at full volume, on the scale of the apu mixer
+)
+
+//amplitudes of the 32 levels of the envelope, 1.5 dB apart, 0 is silent
+var sunsoftLevels [32]float32
+
+func init() {
+	for i := 1; i < 32; i++ {
+		sunsoftLevels[i] = float32(math.Pow(10, -1.5*float64(31-i)/20))
+	}
+}
+
+//Sunsoft5BAudio is the sound chip of the Sunsoft 5B
+type Sunsoft5BAudio struct {
+	address   byte     // register selected by $C000
+	registers [16]byte // tone periods, noise period, mixer, volumes, envelope period and shape
+	divider   int      // cpu cycles since the last tick
+	tones     [3]sunsoftTone
+	noise     sunsoftNoise
+	envelope  sunsoftEnvelope
+}
+
+type sunsoftTone struct {
+	counter uint16
+	high    bool
+}
+
+type sunsoftNoise struct {
+	counter       byte
+	shiftRegister uint32 // 17 bits
+	high          bool
+}
+
+type sunsoftEnvelope struct {
+	counter uint16
+	level   int // 0 to 31
+	rising  bool
+	holding bool
+}
+
+func (audio *Sunsoft5BAudio) reset() {
+	*audio = Sunsoft5BAudio{}
+	audio.noise.shiftRegister = 1
+	audio.registers[7] = 0x3F // tones and noise disabled
+}
+
+//writeRegister write the register selected by the address port, the high nibble of the address must be 0
+func (audio *Sunsoft5BAudio) writeRegister(value byte) {
+	if audio.address >= 0x10 {
+		return
+	}
+	audio.registers[audio.address] = value
+	if audio.address == 0x0D {
+		audio.envelope.restart(value)
+	}
+}
+
+//step tick the channels every sunsoftClockDivider cpu cycles
+func (audio *Sunsoft5BAudio) step() {
+	audio.divider++
+	if audio.divider < sunsoftClockDivider {
+		return
+	}
+	audio.divider = 0
+	for i := range audio.tones {
+		period := uint16(audio.registers[i*2]) | uint16(audio.registers[i*2+1]&0x0F)<<8
+		audio.tones[i].step(period)
+	}
+	audio.noise.step(audio.registers[6] & 0x1F)
+	period := uint16(audio.registers[0x0B]) | uint16(audio.registers[0x0C])<<8
+	audio.envelope.step(period, audio.registers[0x0D])
+}
+
+//output mix the three channels: a channel is heard while its tone and the noise are high, each one can be disabled
+func (audio *Sunsoft5BAudio) output() float32 {
+	mixer := audio.registers[7]
+	var sum float32
+
+	for i, tone := range audio.tones {
+		toneOn := tone.high || mixer&(1<<i) != 0
+		noiseOn := audio.noise.high || mixer&(8<<i) != 0
+		if !toneOn || !noiseOn {
+			continue
+		}
+		volume := audio.registers[8+i]
+		if volume&0x10 != 0 {
+			sum += sunsoftLevels[audio.envelope.level]
+		} else if volume&0x0F != 0 {
+			sum += sunsoftLevels[(volume&0x0F)*2+1]
+		}
+	}
+	return sum * sunsoftChannelLevel
+}
+
+//step toggle the square every period ticks, a square lasts 2 periods
+func (tone *sunsoftTone) step(period uint16) {
+	tone.counter++
+	if tone.counter >= period {
+		tone.counter = 0
+		tone.high = !tone.high
+	}
+}
+
+//step shift the 17 bits register every 2 periods, with a feedback of the bits 0 and 3
+func (noise *sunsoftNoise) step(period byte) {
+	noise.counter++
+	if noise.counter < period*2 && period != 0 {
+		return
+	}
+	noise.counter = 0
+	bit := (noise.shiftRegister ^ noise.shiftRegister>>3) & 1
+	noise.shiftRegister = noise.shiftRegister>>1 | bit<<16
+	noise.high = noise.shiftRegister&1 != 0
+}
+
+//restart the envelope with a new shape: continue, attack, alternate and hold bits
+func (envelope *sunsoftEnvelope) restart(shape byte) {
+	envelope.counter = 0
+	envelope.holding = false
+	envelope.rising = shape&0x04 != 0
+	if envelope.rising {
+		envelope.level = 0
+	} else {
+		envelope.level = 31
+	}
+}
+
+//step move the envelope one level every period ticks, then repeat, alternate or hold at the end of a ramp
+func (envelope *sunsoftEnvelope) step(period uint16, shape byte) {
+	if envelope.holding {
+		return
+	}
+	envelope.counter++
+	if envelope.counter < period {
+		return
+	}
+	envelope.counter = 0
+	if envelope.rising && envelope.level < 31 {
+		envelope.level++
+		return
+	}
+	if !envelope.rising && envelope.level > 0 {
+		envelope.level--
+		return
+	}
+	// end of the ramp
+	continues := shape&0x08 != 0
+	attack := shape&0x04 != 0
+	alternate := shape&0x02 != 0
+	hold := shape&0x01 != 0
+	switch {
+	case !continues:
+		envelope.level = 0
+		envelope.holding = true
+	case hold:
+		if attack != alternate {
+			envelope.level = 31
+		} else {
+			envelope.level = 0
+		}
+		envelope.holding = true
+	case alternate:
+		envelope.rising = !envelope.rising
+	case envelope.rising:
+		envelope.level = 0
+	default:
+		envelope.level = 31
+	}
+}
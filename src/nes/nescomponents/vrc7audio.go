@@ -0,0 +1,266 @@
+package nescomponents
+
+import "math"
+
+//the VRC7 sound is a YM2413 (OPLL) reduced to 6 channels and 15 instruments:
+//each channel is a modulator operator whose sine changes the phase of a carrier operator
+//https://wiki.nesdev.com/w/index.php/VRC7_audio
+const (
+	vrc7CyclesPerSample = 36 // the chip makes a sample every 72 cycles of its 3.58 MHz clock
+	vrc7SampleRate      = apuCpuFrequency / vrc7CyclesPerSample
+	vrc7Silence         = 48.0  // attenuation (dB) of the envelope when it is off
+	vrc7ChannelLevel    = 0.1   // output of a channel at full volume, on the scale of the apu mixer
+	vrc7VibratoRate     = 6.4   // Hz
+	vrc7VibratoDepth    = 0.007 // about 14 cents
+	vrc7TremoloRate     = 3.7   // Hz
+	vrc7TremoloDepth    = 4.8   // dB
+)
+
+//instruments 1 to 15 of the VRC7, in the format of the custom instrument registers $00-$07
+var vrc7Patches = [15][8]byte{
+	{0x03, 0x21, 0x05, 0x06, 0xE8, 0x81, 0x42, 0x27}, // buzzy bell
+	{0x13, 0x41, 0x14, 0x0D, 0xD8, 0xF6, 0x23, 0x12}, // guitar
+	{0x11, 0x11, 0x08, 0x08, 0xFA, 0xB2, 0x20, 0x12}, // wurly
+	{0x31, 0x61, 0x0C, 0x07, 0xA8, 0x64, 0x61, 0x27}, // flute
+	{0x32, 0x21, 0x1E, 0x06, 0xE1, 0x76, 0x01, 0x28}, // clarinet
+	{0x02, 0x01, 0x06, 0x00, 0xA3, 0xE2, 0xF4, 0xF4}, // synth
+	{0x21, 0x61, 0x1D, 0x07, 0x82, 0x81, 0x11, 0x07}, // trumpet
+	{0x23, 0x21, 0x22, 0x17, 0xA2, 0x72, 0x01, 0x17}, // organ
+	{0x35, 0x11, 0x25, 0x00, 0x40, 0x73, 0x72, 0x01}, // bells
+	{0xB5, 0x01, 0x0F, 0x0F, 0xA8, 0xA5, 0x51, 0x02}, // vibes
+	{0x17, 0xC1, 0x24, 0x07, 0xF8, 0xF8, 0x22, 0x12}, // vibraphone
+	{0x71, 0x23, 0x11, 0x06, 0x65, 0x74, 0x18, 0x16}, // tutti
+	{0x01, 0x02, 0xD3, 0x05, 0xC9, 0x95, 0x03, 0x02}, // fretless
+	{0x61, 0x63, 0x0C, 0x00, 0x94, 0xC0, 0x33, 0xF6}, // synth bass
+	{0x21, 0x72, 0x0D, 0x00, 0xC1, 0xD5, 0x56, 0x06}, // sweep
+}
+
+//frequency multipliers of the operators
+var vrc7Multipliers = [16]float64{0.5, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 10, 12, 12, 15, 15}
+
+//attenuation (dB) of the key scale level at the octave 7, by the 4 high bits of the frequency
+var vrc7KeyScaleLevels = [16]float64{0, 18, 24, 27.75, 30, 32.25, 33.75, 35.25, 36, 37.5, 38.25, 39, 39.75, 40.5, 41.25, 42}
+
+//envelope phases of an operator
+const (
+	vrc7Attack = iota
+	vrc7Decay
+	vrc7Sustain
+	vrc7Release
+)
+
+//vrc7Operator is a sine oscillator with an ADSR envelope
+type vrc7Operator struct {
+	phase       float64 // in periods
+	envelope    float64 // attenuation in dB, from 0 to vrc7Silence
+	state       int
+	output      float64
+	lastOutputs [2]float64 // for the feedback of the modulator
+}
+
+//vrc7Channel is a modulator and a carrier
+type vrc7Channel struct {
+	frequency  uint16 // 9 bits
+	octave     byte
+	keyOn      bool
+	sustain    bool // key off uses a slow release
+	instrument byte // 0 for the custom one
+	volume     byte // attenuation of the carrier, 3 dB steps
+	modulator  vrc7Operator
+	carrier    vrc7Operator
+}
+
+//VRC7Audio is the FM synthesizer of the VRC7
+type VRC7Audio struct {
+	address  byte    // register selected by $9010
+	custom   [8]byte // custom instrument
+	channels [6]vrc7Channel
+	cycle    int     // cpu cycles since the last sample
+	time     float64 // seconds, for the vibrato and the tremolo
+	output   float32
+}
+
+//reset silence all the channels
+func (audio *VRC7Audio) reset() {
+	*audio = VRC7Audio{}
+	for i := range audio.channels {
+		audio.channels[i].modulator.envelope = vrc7Silence
+		audio.channels[i].carrier.envelope = vrc7Silence
+		audio.channels[i].modulator.state = vrc7Release
+		audio.channels[i].carrier.state = vrc7Release
+	}
+}
+
+//writeRegister write the register selected by the address port
+func (audio *VRC7Audio) writeRegister(value byte) {
+	address := audio.address
+
+	switch {
+	case address < 0x08:
+		audio.custom[address] = value
+	case address >= 0x10 && address <= 0x15:
+		channel := &audio.channels[address-0x10]
+		channel.frequency = channel.frequency&0x100 | uint16(value)
+	case address >= 0x20 && address <= 0x25:
+		channel := &audio.channels[address-0x20]
+		channel.frequency = channel.frequency&0xFF | uint16(value&1)<<8
+		channel.octave = (value >> 1) & 7
+		channel.sustain = value&0x20 != 0
+		keyOn := value&0x10 != 0
+		if keyOn && !channel.keyOn {
+			channel.modulator.keyOn()
+			channel.carrier.keyOn()
+		} else if !keyOn && channel.keyOn {
+			channel.modulator.state = vrc7Release
+			channel.carrier.state = vrc7Release
+		}
+		channel.keyOn = keyOn
+	case address >= 0x30 && address <= 0x35:
+		channel := &audio.channels[address-0x30]
+		channel.instrument = value >> 4
+		channel.volume = value & 0x0F
+	}
+}
+
+//step make a new sample every vrc7CyclesPerSample cpu cycles
+func (audio *VRC7Audio) step() {
+	audio.cycle++
+	if audio.cycle < vrc7CyclesPerSample {
+		return
+	}
+	audio.cycle = 0
+	audio.time += 1.0 / vrc7SampleRate
+	vibrato := 1 + vrc7VibratoDepth*math.Sin(2*math.Pi*vrc7VibratoRate*audio.time)
+	tremolo := vrc7TremoloDepth * (1 + math.Sin(2*math.Pi*vrc7TremoloRate*audio.time)) / 2
+	var sum float64
+	for i := range audio.channels {
+		sum += audio.channels[i].step(audio.patch(i), vibrato, tremolo)
+	}
+	audio.output = float32(sum * vrc7ChannelLevel)
+}
+
+//patch return the instrument of a channel
+func (audio *VRC7Audio) patch(channel int) *[8]byte {
+	instrument := audio.channels[channel].instrument
+
+	if instrument == 0 {
+		return &audio.custom
+	}
+	return &vrc7Patches[instrument-1]
+}
+
+func (operator *vrc7Operator) keyOn() {
+	operator.phase = 0
+	operator.state = vrc7Attack
+}
+
+//step compute the next sample of the channel, the instrument bytes of the modulator are the even ones
+func (channel *vrc7Channel) step(patch *[8]byte, vibrato, tremolo float64) float64 {
+	frequency := float64(channel.frequency) * float64(int(1)<<channel.octave) / (1 << 19)
+	keyScale := int(channel.octave)<<1 | int(channel.frequency>>8)
+	octaveLevel := math.Max(0, vrc7KeyScaleLevels[channel.frequency>>5]-6*float64(7-channel.octave))
+
+	// modulator: total level in 0.75 dB steps, feedback of its two last outputs
+	modulator := &channel.modulator
+	attenuation := 0.75*float64(patch[2]&0x3F) + keyScaleLevel(patch[2]>>6, octaveLevel)
+	feedback := 0.0
+	if fb := patch[3] & 7; fb != 0 {
+		feedback = (modulator.lastOutputs[0] + modulator.lastOutputs[1]) / 2 * 2 / float64(int(1)<<(7-fb))
+	}
+	modulator.stepEnvelope(patch[0], patch[4], patch[6], channel.sustain, keyScale)
+	modulator.stepOutput(patch[0], frequency, vibrato, tremolo, attenuation, feedback, patch[3]&0x08 != 0)
+	modulator.lastOutputs[1] = modulator.lastOutputs[0]
+	modulator.lastOutputs[0] = modulator.output
+
+	// carrier: volume in 3 dB steps, its phase is moved by the modulator
+	carrier := &channel.carrier
+	attenuation = 3*float64(channel.volume) + keyScaleLevel(patch[3]>>6, octaveLevel)
+	carrier.stepEnvelope(patch[1], patch[5], patch[7], channel.sustain, keyScale)
+	carrier.stepOutput(patch[1], frequency, vibrato, tremolo, attenuation, 4*modulator.output, patch[3]&0x10 != 0)
+	return carrier.output
+}
+
+//keyScaleLevel return the attenuation of the high notes: none, 1.5, 3 or 6 dB per octave
+func keyScaleLevel(ksl byte, octaveLevel float64) float64 {
+	return octaveLevel * [4]float64{0, 0.25, 0.5, 1}[ksl]
+}
+
+//stepOutput move the phase and compute the output, halved sine when the waveform is rectified
+func (operator *vrc7Operator) stepOutput(control byte, frequency, vibrato, tremolo, attenuation, modulation float64, rectified bool) {
+	if control&0x40 != 0 {
+		frequency *= vibrato
+	}
+	if control&0x80 != 0 {
+		attenuation += tremolo
+	}
+	operator.phase += frequency * vrc7Multipliers[control&0x0F]
+	operator.phase -= math.Floor(operator.phase)
+	sine := math.Sin(2 * math.Pi * (operator.phase + modulation))
+	if rectified && sine < 0 {
+		sine = 0
+	}
+	attenuation += operator.envelope
+	if attenuation >= vrc7Silence {
+		operator.output = 0
+		return
+	}
+	operator.output = sine * math.Pow(10, -attenuation/20)
+}
+
+//envelopeRate return the attenuation change per sample (dB) for a rate of 4 bits:
+//a rate of 1 takes about 20 s to go through the 48 dB, each step of the rate is twice as fast every 4 steps of the key scale
+func envelopeRate(rate byte, keyScale int, control byte) float64 {
+	if rate == 0 {
+		return 0
+	}
+	if control&0x10 == 0 {
+		keyScale >>= 2
+	}
+	effective := math.Min(float64(4*int(rate)+keyScale), 63)
+	seconds := 19.64 / math.Pow(2, (effective-4)/4)
+	return vrc7Silence / (seconds * vrc7SampleRate)
+}
+
+//stepEnvelope move the ADSR envelope: attack to 0 dB, decay to the sustain level, then hold it if the instrument
+//is sustained or keep decaying at the release rate. The key off releases at the release rate, or slowly with the sustain
+func (operator *vrc7Operator) stepEnvelope(control, attackDecay, sustainRelease byte, sustain bool, keyScale int) {
+	sustained := control&0x20 != 0
+	sustainLevel := 3 * float64(sustainRelease>>4)
+
+	switch operator.state {
+	case vrc7Attack:
+		if attackDecay>>4 == 15 {
+			operator.envelope = 0 // the fastest rate is immediate
+		} else {
+			// the attack is exponential, fast from the silence and slow near the full level,
+			// about 7 times shorter than a decay at the same rate
+			operator.envelope -= (operator.envelope + 1) * envelopeRate(attackDecay>>4, keyScale, control) * 0.56
+		}
+		if operator.envelope <= 0 {
+			operator.envelope = 0
+			operator.state = vrc7Decay
+		}
+	case vrc7Decay:
+		operator.envelope += envelopeRate(attackDecay&0x0F, keyScale, control)
+		if operator.envelope >= sustainLevel {
+			operator.envelope = sustainLevel
+			operator.state = vrc7Sustain
+		}
+	case vrc7Sustain:
+		if !sustained {
+			operator.envelope += envelopeRate(sustainRelease&0x0F, keyScale, control)
+		}
+	case vrc7Release:
+		switch {
+		case sustain:
+			operator.envelope += envelopeRate(5, keyScale, control)
+		case sustained:
+			operator.envelope += envelopeRate(sustainRelease&0x0F, keyScale, control)
+		default:
+			operator.envelope += envelopeRate(7, keyScale, control)
+		}
+	}
+	if operator.envelope > vrc7Silence {
+		operator.envelope = vrc7Silence
+	}
+}
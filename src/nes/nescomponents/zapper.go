@@ -0,0 +1,76 @@
+package nescomponents
+
+//the photodiode of the zapper sees a small area around where it is aimed,
+//and keeps reporting light for about 20 scanlines after the beam drew a bright pixel
+//https://wiki.nesdev.com/w/index.php/Zapper
+const (
+	zapperRadius      = 2
+	zapperPersistence = 20
+	zapperBrightness  = 85 // minimum luminance (0-255) of a pixel the sensor reacts to
+)
+
+//Zapper is the light gun, usually plugged in port 2
+type Zapper struct {
+	ppu     *PPU
+	x, y    int  // where the gun is aimed, in nes pixels, negative when outside the picture
+	trigger bool // the trigger is pulled
+}
+
+//NewZapper zapper constructor, the light sensor watches what the ppu draws
+func NewZapper(ppu *PPU) *Zapper {
+	return &Zapper{ppu: ppu, x: -1, y: -1}
+}
+
+//Aim set where the gun is aimed, a negative position aims outside the tv
+func (zapper *Zapper) Aim(x int, y int) {
+	zapper.x, zapper.y = x, y
+}
+
+//SetTrigger pull or release the trigger
+func (zapper *Zapper) SetTrigger(pulled bool) {
+	zapper.trigger = pulled
+}
+
+//Read D3: 0 when light is sensed, D4: 1 while the trigger is pulled
+func (zapper *Zapper) Read() byte {
+	var value byte = 0x08
+
+	if zapper.sensesLight() {
+		value = 0
+	}
+	if zapper.trigger {
+		value |= 0x10
+	}
+	return value
+}
+
+//Write the zapper ignores the strobe
+func (zapper *Zapper) Write(value byte) {
+}
+
+//sensesLight look at the pixels around the aimed position the ppu has drawn during the last scanlines,
+//so the result depends on where the beam is when the game reads $4017, like on a crt
+func (zapper *Zapper) sensesLight() bool {
+	ppu := zapper.ppu
+	scanLine := ppu.ScanLine
+
+	if zapper.x < 0 || zapper.y < 0 || scanLine >= 240 {
+		return false
+	}
+	for y := zapper.y - zapperRadius; y <= zapper.y+zapperRadius; y++ {
+		if y < 0 || y >= 240 || y > scanLine || scanLine-y > zapperPersistence {
+			continue
+		}
+		for x := zapper.x - zapperRadius; x <= zapper.x+zapperRadius; x++ {
+			// on the current scanline, only the pixels left of the beam are drawn
+			if x < 0 || x >= 256 || (y == scanLine && x >= ppu.Cycle-1) {
+				continue
+			}
+			c := Palette[ppu.backPixels[y*256+x]]
+			if (299*int(c.R)+587*int(c.G)+114*int(c.B))/1000 >= zapperBrightness {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"github.com/gordonklaus/portaudio"
+)
+
+//largest change of the resampling ratio made by the dynamic rate control, small enough to not be heard
+const maxRateDelta = 0.005
+
+//Audio plays the samples of the apu on the default output device
+type Audio struct {
+	stream     *portaudio.Stream
+	sampleRate float64      // samples per second played by the device
+	channel    chan float32 // samples waiting to be played, filled by the apu
+}
+
+//NewAudio open the default output device, mono, the buffer holds 200ms of sound
+func NewAudio() (*Audio, error) {
+	var audio Audio
+
+	if err := portaudio.Initialize(); err != nil {
+		return nil, err
+	}
+	host, err := portaudio.DefaultHostApi()
+	if err != nil {
+		portaudio.Terminate()
+		return nil, err
+	}
+	parameters := portaudio.HighLatencyParameters(nil, host.DefaultOutputDevice)
+	parameters.Output.Channels = 1
+	audio.sampleRate = parameters.SampleRate
+	audio.channel = make(chan float32, int(audio.sampleRate/5))
+	stream, err := portaudio.OpenStream(parameters, audio.callback)
+	if err == nil {
+		err = stream.Start()
+	}
+	if err != nil {
+		portaudio.Terminate()
+		return nil, err
+	}
+	audio.stream = stream
+	return &audio, nil
+}
+
+//callback give the device the samples it asks for, silence when the emulation is late
+func (audio *Audio) callback(out []float32) {
+	for i := range out {
+		select {
+		case sample := <-audio.channel:
+			out[i] = sample
+		default:
+			out[i] = 0
+		}
+	}
+}
+
+//Fill return how full the buffer is, from 0 to 1
+func (audio *Audio) Fill() float64 {
+	return float64(len(audio.channel)) / float64(cap(audio.channel))
+}
+
+//SampleRate return the rate the apu must produce samples at for the buffer to stay half full
+func (audio *Audio) SampleRate() float64 {
+	return controlRate(audio.sampleRate, audio.Fill())
+}
+
+//controlRate return the sample rate for a buffer filled from 0 to 1: slightly faster when it empties, slightly
+//slower when it fills up, by maxRateDelta at most (dynamic rate control)
+func controlRate(sampleRate float64, fill float64) float64 {
+	return sampleRate * (1 + maxRateDelta*(1-2*fill))
+}
+
+//Flush drop the samples waiting to be played
+func (audio *Audio) Flush() {
+	for {
+		select {
+		case <-audio.channel:
+		default:
+			return
+		}
+	}
+}
+
+//Close stop the sound
+func (audio *Audio) Close() {
+	audio.stream.Close()
+	portaudio.Terminate()
+}
@@ -0,0 +1,29 @@
+package ui
+
+import (
+	"math"
+	"testing"
+)
+
+func TestControlRate(t *testing.T) {
+	tests := []struct {
+		fill float64
+		rate float64
+	}{
+		{0.5, 48000},
+		{0, 48000 * (1 + maxRateDelta)},
+		{1, 48000 * (1 - maxRateDelta)},
+		{0.25, 48000 * (1 + maxRateDelta/2)},
+	}
+
+	for _, test := range tests {
+		if rate := controlRate(48000, test.fill); math.Abs(rate-test.rate) > 1e-9 {
+			t.Errorf("buffer %.0f%% full: %f Hz, want %f Hz", test.fill*100, rate, test.rate)
+		}
+	}
+	for fill := 0.0; fill < 1; fill += 0.1 {
+		if controlRate(48000, fill) <= controlRate(48000, fill+0.1) {
+			t.Errorf("the rate does not slow down as the buffer fills up, at %.0f%%", fill*100)
+		}
+	}
+}
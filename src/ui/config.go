@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//Config is the input configuration of the user, read from a json file
+//nes buttons are named A, B, Select, Start, Up, Down, Left and Right, TurboA and TurboB are the turbo buttons
+type Config struct {
+	Players  []PlayerConfig    `json:"players"`  // players 1 to 4
+	Deadzone float64           `json:"deadzone"` // how far (0-1) an analog stick must be pushed to press a button
+	Hotkeys  map[string]string `json:"hotkeys"`  // action ("reset", "screenshot"...) -> keyboard key
+	Macros   []MacroConfig     `json:"macros"`
+}
+
+//PlayerConfig maps the keyboard and a gamepad to the buttons of a player
+type PlayerConfig struct {
+	Keyboard  map[string]string `json:"keyboard"`   // nes button -> keyboard key ("A", "LeftShift", "KP8"...)
+	Gamepad   int               `json:"gamepad"`    // 0 for the first connected gamepad, 1 for the second one... -1 for none
+	Buttons   map[string]string `json:"buttons"`    // nes button -> gamepad button ("A", "Start", "DpadUp"...)
+	Axes      map[string]string `json:"axes"`       // nes button -> gamepad axis and direction ("LeftX-", "LeftY+"...)
+	TurboRate int               `json:"turbo_rate"` // frames between two toggles of the turbo buttons
+}
+
+//MacroConfig is a sequence of buttons the controller of a player plays when a key is pressed
+type MacroConfig struct {
+	Player int               `json:"player"` // 1 to 4
+	Hotkey string            `json:"hotkey"`
+	Steps  []MacroStepConfig `json:"steps"`
+}
+
+//MacroStepConfig holds nes buttons during some frames, no buttons makes a pause
+type MacroStepConfig struct {
+	Buttons []string `json:"buttons"`
+	Frames  int      `json:"frames"`
+}
+
+//frames between two toggles of the turbo buttons when the config does not say, 15 presses per second
+const defaultTurboRate = 2
+
+//configName is the file in the user config dir ($XDG_CONFIG_HOME or ~/.config on linux)
+const configName = "MyNesEmulator/config.json"
+
+//actions of the hotkeys, "" in the config file unbinds one
+var hotkeyActions = []string{
+	"reset", "palette", "ntsc", "scaler", "screenshot", "record", "pause", "advance", "fastforward", "slowmotion",
+	"diskside", "eject",
+}
+
+//the gamepad layout of every player
+var (
+	defaultGamepadButtons = map[string]string{
+		"A": "B", "B": "A", "Select": "Back", "Start": "Start",
+		"Up": "DpadUp", "Down": "DpadDown", "Left": "DpadLeft", "Right": "DpadRight",
+		"TurboA": "Y", "TurboB": "X",
+	}
+	defaultGamepadAxes = map[string]string{
+		"Up": "LeftY-", "Down": "LeftY+", "Left": "LeftX-", "Right": "LeftX+",
+	}
+)
+
+//DefaultConfig return the bindings used when the user has no config file
+func DefaultConfig() Config {
+	keyboards := []map[string]string{
+		{"A": "A", "B": "S", "Select": "LeftShift", "Start": "Enter", "Up": "Up", "Down": "Down", "Left": "Left", "Right": "Right",
+			"TurboA": "Z", "TurboB": "X"},
+		{"A": "Period", "B": "Comma", "Select": "RightShift", "Start": "RightControl", "Up": "I", "Down": "K", "Left": "J", "Right": "L"},
+		{"A": "W", "B": "Q", "Select": "1", "Start": "2", "Up": "T", "Down": "G", "Left": "F", "Right": "H"},
+		{"A": "KP3", "B": "KP2", "Select": "KP7", "Start": "KP9", "Up": "KP8", "Down": "KP5", "Left": "KP4", "Right": "KP6"},
+	}
+	config := Config{
+		Deadzone: 0.3,
+		Macros:   []MacroConfig{},
+		Hotkeys: map[string]string{
+			"reset": "R", "palette": "P", "ntsc": "N", "scaler": "M", "screenshot": "F12", "record": "F10",
+			"pause": "Space", "advance": "Backslash", "fastforward": "Tab", "slowmotion": "GraveAccent",
+			"diskside": "F5", "eject": "F6",
+		},
+	}
+	for i, keyboard := range keyboards {
+		config.Players = append(config.Players, PlayerConfig{
+			Keyboard:  keyboard,
+			Gamepad:   i,
+			Buttons:   copyBindings(defaultGamepadButtons),
+			Axes:      copyBindings(defaultGamepadAxes),
+			TurboRate: defaultTurboRate,
+		})
+	}
+	return config
+}
+
+func copyBindings(bindings map[string]string) map[string]string {
+	result := make(map[string]string, len(bindings))
+	for button, name := range bindings {
+		result[button] = name
+	}
+	return result
+}
+
+//DefaultConfigPath return where the config file is looked for when no path is given
+func DefaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configName), nil
+}
+
+//LoadConfig read the config file, "" for the default path
+//a missing default config file is created with the default bindings, so the user has something to edit
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+	create := false
+
+	if path == "" {
+		defaultPath, err := DefaultConfigPath()
+		if err != nil {
+			return config, nil
+		}
+		path, create = defaultPath, true
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && create {
+		if err := saveConfig(path, config); err != nil {
+			println(err.Error())
+		}
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	// the hotkeys missing in the file keep their default key, each player is read over its default bindings:
+	// what a player leaves out (gamepad, buttons, axes...) keeps its default
+	defaults := config.Players
+	config.Players = nil
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("%s: %v", path, err)
+	}
+	var file struct {
+		Players []json.RawMessage `json:"players"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return config, fmt.Errorf("%s: %v", path, err)
+	}
+	config.Players = defaults
+	if len(file.Players) > len(defaults) {
+		return config, fmt.Errorf("%s: %d players configured, at most %d are supported", path, len(file.Players), len(defaults))
+	}
+	for i, player := range file.Players {
+		if err := json.Unmarshal(player, &config.Players[i]); err != nil {
+			return config, fmt.Errorf("%s: player %d: %v", path, i+1, err)
+		}
+	}
+	return config, nil
+}
+
+//saveConfig write a config file, creating its directory
+func saveConfig(path string, config Config) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,330 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/hadi-ilies/MyNesEmulator/src/nes"
+	"github.com/hadi-ilies/MyNesEmulator/src/nes/nescomponents"
+)
+
+//the turbo buttons are bound after the 8 nes buttons
+const (
+	turboA       = 8
+	turboB       = 9
+	bindingCount = 10
+)
+
+//names of the nes buttons in the config file, indexed by nes.KeyA, nes.KeyB... then turboA and turboB
+var buttonNames = [bindingCount]string{
+	nes.KeyA:      "A",
+	nes.KeyB:      "B",
+	nes.KeySelect: "Select",
+	nes.KeyStart:  "Start",
+	nes.KeyUp:     "Up",
+	nes.KeyDown:   "Down",
+	nes.KeyLeft:   "Left",
+	nes.KeyRight:  "Right",
+	turboA:        "TurboA",
+	turboB:        "TurboB",
+}
+
+//names of the keyboard keys in the config file, the letters, digits, F1-F25 and KP0-KP9 are added by init
+var keyNames = map[string]glfw.Key{
+	"Space": glfw.KeySpace, "Apostrophe": glfw.KeyApostrophe, "Comma": glfw.KeyComma, "Minus": glfw.KeyMinus,
+	"Period": glfw.KeyPeriod, "Slash": glfw.KeySlash, "Semicolon": glfw.KeySemicolon, "Equal": glfw.KeyEqual,
+	"LeftBracket": glfw.KeyLeftBracket, "Backslash": glfw.KeyBackslash, "RightBracket": glfw.KeyRightBracket,
+	"GraveAccent": glfw.KeyGraveAccent, "Escape": glfw.KeyEscape, "Enter": glfw.KeyEnter, "Tab": glfw.KeyTab,
+	"Backspace": glfw.KeyBackspace, "Insert": glfw.KeyInsert, "Delete": glfw.KeyDelete,
+	"Right": glfw.KeyRight, "Left": glfw.KeyLeft, "Down": glfw.KeyDown, "Up": glfw.KeyUp,
+	"PageUp": glfw.KeyPageUp, "PageDown": glfw.KeyPageDown, "Home": glfw.KeyHome, "End": glfw.KeyEnd,
+	"CapsLock": glfw.KeyCapsLock, "Pause": glfw.KeyPause, "KPEnter": glfw.KeyKPEnter,
+	"LeftShift": glfw.KeyLeftShift, "LeftControl": glfw.KeyLeftControl, "LeftAlt": glfw.KeyLeftAlt,
+	"RightShift": glfw.KeyRightShift, "RightControl": glfw.KeyRightControl, "RightAlt": glfw.KeyRightAlt,
+}
+
+//names of the gamepad buttons and axes in the config file, with the xbox layout
+var (
+	gamepadButtonNames = map[string]glfw.GamepadButton{
+		"A": glfw.ButtonA, "B": glfw.ButtonB, "X": glfw.ButtonX, "Y": glfw.ButtonY,
+		"LeftBumper": glfw.ButtonLeftBumper, "RightBumper": glfw.ButtonRightBumper,
+		"Back": glfw.ButtonBack, "Start": glfw.ButtonStart, "Guide": glfw.ButtonGuide,
+		"LeftThumb": glfw.ButtonLeftThumb, "RightThumb": glfw.ButtonRightThumb,
+		"DpadUp": glfw.ButtonDpadUp, "DpadRight": glfw.ButtonDpadRight,
+		"DpadDown": glfw.ButtonDpadDown, "DpadLeft": glfw.ButtonDpadLeft,
+	}
+	gamepadAxisNames = map[string]glfw.GamepadAxis{
+		"LeftX": glfw.AxisLeftX, "LeftY": glfw.AxisLeftY, "RightX": glfw.AxisRightX, "RightY": glfw.AxisRightY,
+		"LeftTrigger": glfw.AxisLeftTrigger, "RightTrigger": glfw.AxisRightTrigger,
+	}
+)
+
+func init() {
+	for c := 'A'; c <= 'Z'; c++ {
+		keyNames[string(c)] = glfw.KeyA + glfw.Key(c-'A')
+	}
+	for i := 0; i <= 9; i++ {
+		keyNames[fmt.Sprint(i)] = glfw.Key0 + glfw.Key(i)
+		keyNames[fmt.Sprint("KP", i)] = glfw.KeyKP0 + glfw.Key(i)
+	}
+	for i := 1; i <= 25; i++ {
+		keyNames[fmt.Sprint("F", i)] = glfw.KeyF1 + glfw.Key(i-1)
+	}
+}
+
+//axisBinding is a direction of a gamepad axis
+type axisBinding struct {
+	axis      glfw.GamepadAxis
+	direction float32 // -1 or 1, 0 when the button has no axis
+}
+
+//playerControls are the bindings of a player, checked and ready to be read every frame
+type playerControls struct {
+	keys      [bindingCount]glfw.Key           // glfw.KeyUnknown when not bound
+	gamepad   int                              // index in the connected gamepads, -1 for none
+	buttons   [bindingCount]glfw.GamepadButton // -1 when not bound
+	axes      [bindingCount]axisBinding
+	turboRate int
+}
+
+//macro is a sequence of buttons played by the controller of a player
+type macro struct {
+	player int
+	steps  []nescomponents.MacroStep
+}
+
+//Controls turn the keyboard and the gamepads into nes buttons and hotkeys, as the config says
+type Controls struct {
+	players  []playerControls
+	deadzone float32
+	hotkeys  map[glfw.Key]string // key -> action
+	macros   map[glfw.Key]macro  // key -> macro
+}
+
+//NewControls check the names of the config and build the controls
+func NewControls(config Config) (*Controls, error) {
+	var controls Controls
+
+	controls.deadzone = float32(config.Deadzone)
+	controls.hotkeys = make(map[glfw.Key]string)
+	for action, name := range config.Hotkeys {
+		if !isHotkeyAction(action) {
+			return nil, fmt.Errorf("unknown hotkey action %q, expected one of %q", action, hotkeyActions)
+		}
+		key, err := lookupKey(name)
+		if err != nil {
+			return nil, fmt.Errorf("hotkey %s: %v", action, err)
+		}
+		if key != glfw.KeyUnknown {
+			controls.hotkeys[key] = action
+		}
+	}
+	for i, player := range config.Players {
+		bindings, err := newPlayerControls(player)
+		if err != nil {
+			return nil, fmt.Errorf("player %d: %v", i+1, err)
+		}
+		controls.players = append(controls.players, bindings)
+	}
+	controls.macros = make(map[glfw.Key]macro)
+	for i, config := range config.Macros {
+		key, result, err := newMacro(config, len(controls.players))
+		if err != nil {
+			return nil, fmt.Errorf("macro %d: %v", i+1, err)
+		}
+		controls.macros[key] = result
+	}
+	return &controls, nil
+}
+
+func newMacro(config MacroConfig, players int) (glfw.Key, macro, error) {
+	result := macro{player: config.Player - 1}
+
+	if result.player < 0 || result.player >= players {
+		return glfw.KeyUnknown, result, fmt.Errorf("player %d does not exist", config.Player)
+	}
+	key, err := lookupKey(config.Hotkey)
+	if err != nil || key == glfw.KeyUnknown {
+		return glfw.KeyUnknown, result, fmt.Errorf("invalid hotkey %q", config.Hotkey)
+	}
+	for _, stepConfig := range config.Steps {
+		step := nescomponents.MacroStep{Frames: stepConfig.Frames}
+		for _, name := range stepConfig.Buttons {
+			i, err := buttonIndex(name)
+			if err != nil || i >= 8 {
+				return key, result, fmt.Errorf("unknown nes button %q", name)
+			}
+			step.Buttons[i] = true
+		}
+		result.steps = append(result.steps, step)
+	}
+	return key, result, nil
+}
+
+//buttonIndex return the index (nes.KeyA...) of a nes button name
+func buttonIndex(name string) (int, error) {
+	for i, buttonName := range buttonNames {
+		if buttonName == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown nes button %q", name)
+}
+
+func newPlayerControls(config PlayerConfig) (playerControls, error) {
+	var player playerControls
+
+	player.gamepad = config.Gamepad
+	player.turboRate = config.TurboRate
+	if player.turboRate < 1 {
+		player.turboRate = defaultTurboRate
+	}
+	for i := range buttonNames {
+		player.keys[i] = glfw.KeyUnknown
+		player.buttons[i] = -1
+	}
+	for button, name := range config.Keyboard {
+		i, err := buttonIndex(button)
+		if err != nil {
+			return player, err
+		}
+		if player.keys[i], err = lookupKey(name); err != nil {
+			return player, err
+		}
+	}
+	for button, name := range config.Buttons {
+		i, err := buttonIndex(button)
+		if err != nil {
+			return player, err
+		}
+		gamepadButton, found := gamepadButtonNames[name]
+		if !found {
+			return player, fmt.Errorf("unknown gamepad button %q", name)
+		}
+		player.buttons[i] = gamepadButton
+	}
+	for button, name := range config.Axes {
+		i, err := buttonIndex(button)
+		if err != nil {
+			return player, err
+		}
+		direction := float32(1)
+		if strings.HasSuffix(name, "-") {
+			direction = -1
+		}
+		axis, found := gamepadAxisNames[strings.TrimRight(name, "+-")]
+		if !found || !(strings.HasSuffix(name, "-") || strings.HasSuffix(name, "+")) {
+			return player, fmt.Errorf("unknown gamepad axis %q, expected an axis followed by + or -", name)
+		}
+		player.axes[i] = axisBinding{axis, direction}
+	}
+	return player, nil
+}
+
+//lookupKey return the keyboard key of a name, "" for none
+func lookupKey(name string) (glfw.Key, error) {
+	if name == "" {
+		return glfw.KeyUnknown, nil
+	}
+	key, found := keyNames[name]
+	if !found {
+		return glfw.KeyUnknown, fmt.Errorf("unknown key %q", name)
+	}
+	return key, nil
+}
+
+//isHotkeyAction tell whether an action can be bound to a hotkey
+func isHotkeyAction(action string) bool {
+	for _, name := range hotkeyActions {
+		if name == action {
+			return true
+		}
+	}
+	return false
+}
+
+//Hotkey return the action bound to a key, "" if none
+func (controls *Controls) Hotkey(key glfw.Key) string {
+	return controls.hotkeys[key]
+}
+
+//PlayMacro start the macro bound to a key, false if there is none
+func (controls *Controls) PlayMacro(key glfw.Key, console *nes.Nes) bool {
+	macro, found := controls.macros[key]
+	if found {
+		console.PlayMacro(macro.player, macro.steps)
+	}
+	return found
+}
+
+//connectedGamepads return the joysticks with a gamepad mapping, ordered by joystick id
+//they are listed every frame, so gamepads can be plugged and unplugged while playing
+func connectedGamepads() []glfw.Joystick {
+	var gamepads []glfw.Joystick
+
+	for joystick := glfw.Joystick1; joystick <= glfw.JoystickLast; joystick++ {
+		if joystick.Present() && joystick.IsGamepad() {
+			gamepads = append(gamepads, joystick)
+		}
+	}
+	return gamepads
+}
+
+//onJoystick tell the user when a gamepad is plugged or unplugged
+func onJoystick(joystick glfw.Joystick, event glfw.PeripheralEvent) {
+	switch event {
+	case glfw.Connected:
+		if joystick.IsGamepad() {
+			println("gamepad connected:", joystick.GetGamepadName())
+		} else {
+			println("joystick connected without gamepad mapping:", joystick.GetName())
+		}
+	case glfw.Disconnected:
+		println("gamepad disconnected")
+	}
+}
+
+//UpdatePlayer give the buttons of a player, pressed on the keyboard or on its gamepad, to its controller
+func (controls *Controls) UpdatePlayer(window *glfw.Window, gamepads []glfw.Joystick, console *nes.Nes, player int) {
+	var buttons [8]byte
+	var turbo [8]bool
+
+	if player >= len(controls.players) {
+		return
+	}
+	bindings := controls.players[player]
+	pressed := controls.readPlayer(window, gamepads, bindings)
+	for i := range buttons {
+		buttons[i] = 1
+		if pressed[i] {
+			buttons[i] = 0
+		}
+	}
+	turbo[nes.KeyA] = pressed[turboA]
+	turbo[nes.KeyB] = pressed[turboB]
+	console.SetButtonToPlayer(player, buttons)
+	console.SetTurboToPlayer(player, turbo, bindings.turboRate)
+}
+
+//readPlayer return which bindings of a player are pressed
+func (controls *Controls) readPlayer(window *glfw.Window, gamepads []glfw.Joystick, bindings playerControls) [bindingCount]bool {
+	var result [bindingCount]bool
+	var state *glfw.GamepadState
+
+	if bindings.gamepad >= 0 && bindings.gamepad < len(gamepads) {
+		state = gamepads[bindings.gamepad].GetGamepadState()
+	}
+	for i := range result {
+		pressed := bindings.keys[i] != glfw.KeyUnknown && window.GetKey(bindings.keys[i]) == glfw.Press
+		if state != nil && bindings.buttons[i] >= 0 && state.Buttons[bindings.buttons[i]] == glfw.Press {
+			pressed = true
+		}
+		if state != nil && bindings.axes[i].direction != 0 &&
+			state.Axes[bindings.axes[i].axis]*bindings.axes[i].direction > controls.deadzone {
+			pressed = true
+		}
+		result[i] = pressed
+	}
+	return result
+}
@@ -5,26 +5,45 @@ import (
 	//	"os"
 
 	oglEncap "./openglencapsulation" // import and rename the package openglencapsulation to oglEncap
-	"github.com/go-gl/gl/v2.1/gl"
+	"./scalers"
+	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/hadi-ilies/MyNesEmulator/src/nes"
+	"github.com/hadi-ilies/MyNesEmulator/src/nes/nescomponents"
 )
 
 //GameView struct that reprensent the gameview
 type GameView struct {
-	nes     *nes.Nes
-	ui      *Ui // lol there is no inerittance in golang, I am a noob ':(
-	texture uint32
-	frames  []image.Image
+	nes        *nes.Nes
+	ui         *Ui // lol there is no inerittance in golang, I am a noob ':(
+	frames     []image.Image
+	palette    int                       // palette in use
+	ntscMode   string                    // ntsc filter in use
+	ntscFilter *nescomponents.NtscFilter // nil when the frames are displayed as they are
+	scalerMode string                    // software scaler in use
+	scaler     *scalers.Scaler           // nil when the frames are not scaled on the cpu
+	recording  bool                      // frames are kept to be saved as a gif
+	recorded   int                       // frames emulated since the recording started
+	zapper     *nescomponents.Zapper     // nil when no zapper is plugged
+	devices    []deviceInput             // devices other than the controllers, fed from the mouse and the keyboard
+	typing     bool                      // the family basic keyboard uses the whole keyboard, letter hotkeys are off
+	speed      emulationSpeed            // pause, fast forward and slow motion
+	frameTime  float64                   // emulated time not run yet, less than a frame
 }
 
 //NewGameView gameview constructor
 func NewGameView(ui *Ui, nes *nes.Nes) View {
 	var gameView GameView
 
-	gameView.texture = oglEncap.CreateTexture()
 	gameView.nes = nes
 	gameView.ui = ui
+	gameView.palette = paletteFromOptions(ui.options)
+	gameView.ntscMode = ui.options.Ntsc
+	gameView.ntscFilter, _ = newNtscFilter(gameView.ntscMode, ui.options)
+	gameView.scalerMode = ui.options.Scaler
+	gameView.scaler, _ = newScaler(gameView.scalerMode)
+	gameView.plugInputDevices()
+	gameView.speed.fastRate = ui.options.FastForward
 	return &gameView
 }
 
@@ -33,86 +52,113 @@ func (gameView *GameView) Start() {
 	//todo setTitle here
 	gameView.ui.GetWindow().SetKeyCallback(gameView.onKey) // todo getWindow can be removed
 	gameView.nes.Reset()                                   //init nes
+	if gameView.zapper != nil {
+		gameView.ui.GetWindow().SetCursor(glfw.CreateStandardCursor(glfw.CrosshairCursor))
+	}
 }
 
 func (gameView *GameView) Update(dt float64) {
-	if dt > 1 {
+	if dt > 1 { // the window was stuck, do not catch up
 		dt = 0
+		gameView.frameTime = 0
+	}
+	gameView.updateControllers()
+	for _, device := range gameView.devices {
+		device(gameView.ui.GetWindow(), gameView.ui.options.Display)
 	}
-	updateControllers(gameView.ui.GetWindow(), gameView.nes) // todo code this func
-	gameView.nes.Run(dt)
-	gl.BindTexture(gl.TEXTURE_2D, gameView.texture)
-	oglEncap.SetTexture(gameView.nes.PixelBuffer()) //todo code the buffer
-	gameView.drawBuffer(gameView.ui.GetWindow().GetFramebufferSize())
-	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gameView.run(dt)
+	gameView.drawBuffer(gameView.frame())
 }
 
 func (gameView *GameView) End() {
+	if err := gameView.nes.SaveBattery(); err != nil {
+		println(err.Error())
+	}
 	gameView.ui.GetWindow().SetKeyCallback(nil)
+	gameView.ui.GetWindow().SetCursor(nil)
 }
 
 //will be useful when i will emulate controllers and physics interactions with my nes
 /** PRIVATE METHODS **/
 func (view *GameView) onKey(window *glfw.Window, key glfw.Key, scancode int, action glfw.Action, mods glfw.ModifierKey) {
+	// while typing on the family basic keyboard, only F9-F12 can be hotkeys
+	if view.typing && (key < glfw.KeyF9 || key > glfw.KeyF12) {
+		return
+	}
+	if action == glfw.Press && view.ui.controls.PlayMacro(key, view.nes) {
+		return
+	}
 	if action == glfw.Press { // if key is pressed
-		switch key { //check which key has been pressed
-		case glfw.KeyR: // if i pressed r key i will restart my nes
+		action := view.ui.controls.Hotkey(key)
+		if view.onSpeedHotkey(action) {
+			return
+		}
+		switch action { //check which action the key is bound to
+		case "reset": // restart my nes, the sound queued before does not belong to the new run
 			view.nes.Reset()
+			view.flushSound()
+		case "palette": // switch between the built-in, ntsc and .pal file palettes
+			view.palette = nextPalette(view.palette, view.ui.options)
+			if err := usePalette(view.palette, view.ui.options); err != nil {
+				println(err.Error())
+			}
+		case "screenshot": // save what is on screen in a png
+			screenShot := oglEncap.TakeScreenShot(view.frame(), view.ui.options.Display)
+			if err := oglEncap.SaveScreenShot(screenShot); err != nil {
+				println(err.Error())
+			}
+		case "record": // start or stop recording a gif
+			if view.recording {
+				view.stopRecording()
+			} else {
+				view.recording = true
+				view.recorded = 0
+			}
+		case "ntsc": // switch between no filter and the composite, s-video and rgb ntsc filters
+			view.ntscMode = nextNtscMode(view.ntscMode)
+			view.ntscFilter, _ = newNtscFilter(view.ntscMode, view.ui.options)
+		case "scaler": // switch between the software scalers
+			view.scalerMode = nextScalerMode(view.scalerMode)
+			view.scaler, _ = newScaler(view.scalerMode)
+		case "diskside": // famicom disk system: eject the disk and insert the next side
+			view.nes.InsertNextDiskSide()
+		case "eject": // famicom disk system: eject the disk or insert it back
+			view.nes.EjectDisk()
 		}
 	}
 }
 
-func (view *GameView) drawBuffer(bufferWidth int, bufferHeight int) {
-	padding := 0
-	s1 := float32(bufferWidth) / 256
-	s2 := float32(bufferHeight) / 240
-	f := float32(1 - padding)
-	var x, y float32
-	if s1 >= s2 {
-		x = f * s2 / s1
-		y = f
-	} else {
-		x = f
-		y = f * s1 / s2
+//record keep an emulated frame out of oglEncap.RecordFrameStep, the gif is saved when it is full
+func (view *GameView) record() {
+	if view.recorded%oglEncap.RecordFrameStep == 0 {
+		view.frames = append(view.frames, oglEncap.TakeScreenShot(view.frame(), view.ui.options.Display))
 	}
-	gl.Begin(gl.QUADS)
-	gl.TexCoord2f(0, 1)
-	gl.Vertex2f(-x, -y)
-	gl.TexCoord2f(1, 1)
-	gl.Vertex2f(x, -y)
-	gl.TexCoord2f(1, 0)
-	gl.Vertex2f(x, y)
-	gl.TexCoord2f(0, 0)
-	gl.Vertex2f(-x, y)
-	gl.End()
-}
-
-func readKey(window *glfw.Window, key glfw.Key) byte {
-	if (window.GetKey(key) == glfw.Press) == true {
-		return 0
+	view.recorded++
+	if len(view.frames) >= oglEncap.MaxRecordedFrames {
+		view.stopRecording()
 	}
-	return 1
 }
 
-func readKeys(window *glfw.Window) [8]byte {
-	var result [8]byte
+//stopRecording save the recorded frames in a gif
+func (view *GameView) stopRecording() {
+	oglEncap.Record(view.frames)
+	view.frames = nil
+	view.recording = false
+}
 
-	result[nes.KeyA] = readKey(window, glfw.KeyA)
-	result[nes.KeyB] = readKey(window, glfw.KeyS)
-	result[nes.KeySelect] = readKey(window, glfw.KeyLeftShift)
-	result[nes.KeyStart] = readKey(window, glfw.KeyEnter)
-	result[nes.KeyUp] = readKey(window, glfw.KeyUp)
-	result[nes.KeyDown] = readKey(window, glfw.KeyDown)
-	result[nes.KeyLeft] = readKey(window, glfw.KeyLeft)
-	result[nes.KeyRight] = readKey(window, glfw.KeyRight)
-	// println()
-	// for _, value := range result {
-	// 	print(" ", value, " ")
-	// }
-	// println()
-	return result
+//draw the visible part of the frame through the shader chain, shaped and centered as the display options say
+func (view *GameView) drawBuffer(frame *image.RGBA) {
+	bufferWidth, bufferHeight := view.ui.GetWindow().GetFramebufferSize()
+	if err := view.ui.renderer.Draw(frame, view.ui.options.Display, bufferWidth, bufferHeight); err != nil {
+		println(err.Error())
+	}
 }
 
-func updateControllers(window *glfw.Window, nes *nes.Nes) {
-	nes.SetButtonToController(readKeys(window))
+//updateControllers give the buttons pressed on the keyboard and the gamepads to the controllers of the 4 players
+func (view *GameView) updateControllers() {
+	gamepads := connectedGamepads()
+
+	for player := 0; player < 4; player++ {
+		view.ui.controls.UpdatePlayer(view.ui.GetWindow(), gamepads, view.nes, player)
+	}
 }
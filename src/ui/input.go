@@ -0,0 +1,160 @@
+package ui
+
+import (
+	"fmt"
+
+	oglEncap "./openglencapsulation"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/hadi-ilies/MyNesEmulator/src/nes/nescomponents"
+)
+
+//devices the user can plug, "" lets the header of the game decide (the four players adapters are in multitap.go)
+var (
+	port2Devices     = []string{"", "controller", "zapper", "arkanoid", "powerpad"}
+	expansionDevices = []string{"", "none", "arkanoid", "trainer", "keyboard"}
+)
+
+//inputSetup says what is plugged in the console, "" for the standard controllers
+type inputSetup struct {
+	multitap  string // "fourscore" or "hori"
+	port2     string // "zapper", "arkanoid" or "powerpad"
+	expansion string // "arkanoid", "trainer" or "keyboard"
+}
+
+//deviceInput feeds a device plugged in the console from the mouse and the keyboard, once per frame
+type deviceInput func(window *glfw.Window, display oglEncap.Display)
+
+//checkChoice tell whether a value is one of the choices of an option
+func checkChoice(option string, value string, choices []string) error {
+	for _, choice := range choices {
+		if choice == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown %s %q, expected one of %q", option, value, choices[1:])
+}
+
+//checkInput tell whether the devices chosen by the user exist and can be plugged together
+func checkInput(options Options) error {
+	if err := checkChoice("four players adapter", options.Multitap, multitaps); err != nil {
+		return err
+	}
+	if err := checkChoice("port 2 device", options.Port2, port2Devices); err != nil {
+		return err
+	}
+	if err := checkChoice("expansion device", options.Expansion, expansionDevices); err != nil {
+		return err
+	}
+	if options.Multitap == "fourscore" && options.Port2 != "" && options.Port2 != "controller" {
+		return fmt.Errorf("the four score already uses port 2, it can not be used with a %s", options.Port2)
+	}
+	if options.Multitap == "hori" && options.Expansion != "" && options.Expansion != "none" {
+		return fmt.Errorf("the hori adapter already uses the expansion port, it can not be used with a %s", options.Expansion)
+	}
+	return nil
+}
+
+//headerInputSetup return the devices a game asks for in its nes 2.0 header
+func headerInputSetup(device byte) inputSetup {
+	switch device {
+	case nescomponents.InputFourScore:
+		return inputSetup{multitap: "fourscore"}
+	case nescomponents.InputFamicom4Players:
+		return inputSetup{multitap: "hori"}
+	case nescomponents.InputZapper:
+		return inputSetup{port2: "zapper"}
+	case nescomponents.InputArkanoidNes:
+		return inputSetup{port2: "arkanoid"}
+	case nescomponents.InputPowerPadA, nescomponents.InputPowerPadB:
+		return inputSetup{port2: "powerpad"}
+	case nescomponents.InputArkanoidFamicom:
+		return inputSetup{expansion: "arkanoid"}
+	case nescomponents.InputFamilyTrainerA, nescomponents.InputFamilyTrainerB:
+		return inputSetup{expansion: "trainer"}
+	case nescomponents.InputFamilyKeyboard:
+		return inputSetup{expansion: "keyboard"}
+	}
+	return inputSetup{}
+}
+
+//newInputSetup return the devices to plug, the options of the user win over the header of the game
+func newInputSetup(options Options, device byte) inputSetup {
+	setup := headerInputSetup(device)
+
+	if options.Multitap != "" {
+		setup.multitap = options.Multitap
+	}
+	if options.Port2 != "" {
+		setup.port2 = options.Port2
+	}
+	if options.Expansion != "" {
+		setup.expansion = options.Expansion
+	}
+	if setup.multitap == "none" {
+		setup.multitap = ""
+	}
+	if setup.port2 == "controller" {
+		setup.port2 = ""
+	}
+	if setup.expansion == "none" {
+		setup.expansion = ""
+	}
+	return setup
+}
+
+//plugInputDevices plug the devices of the game and of the options in the console
+func (view *GameView) plugInputDevices() {
+	console := view.nes
+	setup := newInputSetup(view.ui.options, console.GetInputDevice())
+
+	plugMultitap(console, setup.multitap)
+	switch setup.port2 {
+	case "zapper":
+		zapper := console.UseZapper()
+		view.zapper = zapper
+		view.devices = append(view.devices, func(window *glfw.Window, display oglEncap.Display) {
+			aimZapper(window, zapper, display)
+		})
+	case "arkanoid":
+		paddle := nescomponents.NewArkanoidController(false)
+		console.PlugDevice(nescomponents.Port2, nescomponents.OnPort(paddle, nescomponents.Port2))
+		view.devices = append(view.devices, func(window *glfw.Window, display oglEncap.Display) {
+			turnPaddle(window, paddle, display)
+		})
+	case "powerpad":
+		mat := nescomponents.NewPowerPad()
+		console.PlugDevice(nescomponents.Port2, mat)
+		view.devices = append(view.devices, func(window *glfw.Window, display oglEncap.Display) {
+			mat.SetButtons(readMat(window))
+		})
+	}
+	switch setup.expansion {
+	case "arkanoid":
+		paddle := nescomponents.NewArkanoidController(true)
+		console.PlugExpansionDevice(paddle)
+		view.devices = append(view.devices, func(window *glfw.Window, display oglEncap.Display) {
+			turnPaddle(window, paddle, display)
+		})
+	case "trainer":
+		mat := nescomponents.NewFamilyTrainer()
+		console.PlugExpansionDevice(mat)
+		view.devices = append(view.devices, func(window *glfw.Window, display oglEncap.Display) {
+			mat.SetButtons(readMat(window))
+		})
+	case "keyboard":
+		keyboard := nescomponents.NewFamilyKeyboard()
+		console.PlugExpansionDevice(keyboard)
+		view.typing = true
+		view.devices = append(view.devices, func(window *glfw.Window, display oglEncap.Display) {
+			keyboard.SetKeys(readFamilyKeyboard(window))
+		})
+	}
+}
+
+//turnPaddle turn the knob of the arkanoid controller as the mouse moves across the picture, the left button fires
+func turnPaddle(window *glfw.Window, paddle *nescomponents.ArkanoidController, display oglEncap.Display) {
+	if x, _, inside := cursorOnFrame(window, display); inside {
+		paddle.SetPosition(byte(x))
+	}
+	paddle.SetFire(window.GetMouseButton(glfw.MouseButtonLeft) == glfw.Press)
+}
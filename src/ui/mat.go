@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/hadi-ilies/MyNesEmulator/src/nes/nescomponents"
+)
+
+//keys of the 12 buttons of the power pad and the family trainer, laid out like the mat
+var matKeys = [12]glfw.Key{
+	glfw.Key5, glfw.Key6, glfw.Key7, glfw.Key8,
+	glfw.KeyT, glfw.KeyY, glfw.KeyU, glfw.KeyI,
+	glfw.KeyG, glfw.KeyH, glfw.KeyJ, glfw.KeyK,
+}
+
+//keys of the family basic keyboard matrix, indexed like nescomponents.FamilyKeyboard.SetKeys
+//the keys missing on a pc keyboard use the closest one: ¥ is backslash, _ is right control, STOP is end,
+//KANA is right alt, GRPH is left alt, CLR is home and DEL is backspace
+var familyKeyboardKeys = [nescomponents.FamilyKeyboardKeys]glfw.Key{
+	glfw.KeyRightBracket, glfw.KeyLeftBracket, glfw.KeyEnter, glfw.KeyF8, glfw.KeyEnd, glfw.KeyBackslash, glfw.KeyRightShift, glfw.KeyRightAlt,
+	glfw.KeySemicolon, glfw.KeyApostrophe, glfw.KeyGraveAccent, glfw.KeyF7, glfw.KeyEqual, glfw.KeyMinus, glfw.KeySlash, glfw.KeyRightControl,
+	glfw.KeyK, glfw.KeyL, glfw.KeyO, glfw.KeyF6, glfw.Key0, glfw.KeyP, glfw.KeyComma, glfw.KeyPeriod,
+	glfw.KeyJ, glfw.KeyU, glfw.KeyI, glfw.KeyF5, glfw.Key8, glfw.Key9, glfw.KeyN, glfw.KeyM,
+	glfw.KeyH, glfw.KeyG, glfw.KeyY, glfw.KeyF4, glfw.Key6, glfw.Key7, glfw.KeyV, glfw.KeyB,
+	glfw.KeyD, glfw.KeyR, glfw.KeyT, glfw.KeyF3, glfw.Key4, glfw.Key5, glfw.KeyC, glfw.KeyF,
+	glfw.KeyA, glfw.KeyS, glfw.KeyW, glfw.KeyF2, glfw.Key3, glfw.KeyE, glfw.KeyZ, glfw.KeyX,
+	glfw.KeyLeftControl, glfw.KeyQ, glfw.KeyEscape, glfw.KeyF1, glfw.Key2, glfw.Key1, glfw.KeyLeftAlt, glfw.KeyLeftShift,
+	glfw.KeyLeft, glfw.KeyRight, glfw.KeyUp, glfw.KeyHome, glfw.KeyInsert, glfw.KeyBackspace, glfw.KeySpace, glfw.KeyDown,
+}
+
+//readMat return the buttons of the mat that are stepped on
+func readMat(window *glfw.Window) [12]bool {
+	var buttons [12]bool
+
+	for i, key := range matKeys {
+		buttons[i] = window.GetKey(key) == glfw.Press
+	}
+	return buttons
+}
+
+//readFamilyKeyboard return the pressed keys of the family basic keyboard
+func readFamilyKeyboard(window *glfw.Window) [nescomponents.FamilyKeyboardKeys]bool {
+	var keys [nescomponents.FamilyKeyboardKeys]bool
+
+	for i, key := range familyKeyboardKeys {
+		keys[i] = window.GetKey(key) == glfw.Press
+	}
+	return keys
+}
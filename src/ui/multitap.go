@@ -0,0 +1,18 @@
+package ui
+
+import (
+	"github.com/hadi-ilies/MyNesEmulator/src/nes"
+)
+
+//four players adapters, "" lets the header of the game decide, "none" plugs the controllers in the ports directly
+var multitaps = []string{"", "none", "fourscore", "hori"}
+
+//plugMultitap plug a four players adapter in the console
+func plugMultitap(console *nes.Nes, multitap string) {
+	switch multitap {
+	case "fourscore":
+		console.UseFourScore()
+	case "hori":
+		console.UseHoriAdapter()
+	}
+}
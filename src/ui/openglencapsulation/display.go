@@ -0,0 +1,117 @@
+package openglencapsulation
+
+import (
+	"image"
+	"math"
+)
+
+// size of a nes frame
+const (
+	nesWidth  = 256
+	nesHeight = 240
+)
+
+//Display says which part of the frame is shown and how it is shaped
+type Display struct {
+	Top, Bottom, Left, Right int  // overscan cropped on each edge, in nes pixels
+	PixelAspect              bool // display 8:7 pixels like a tv instead of square ones
+	IntegerScale             bool // only scale by a whole number so every pixel has the same size
+	Stretch                  bool // fill the whole window, whatever the aspect ratio
+}
+
+//NewDisplay return the default display: the top and bottom 8 lines are hidden like on most tvs
+func NewDisplay() Display {
+	return Display{Top: 8, Bottom: 8}
+}
+
+//visible return the size in nes pixels of the frame once cropped
+func (display Display) visible() (int, int) {
+	width := nesWidth - display.Left - display.Right
+	height := nesHeight - display.Top - display.Bottom
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+	return width, height
+}
+
+//Aspect return the width/height ratio the cropped frame must be displayed with
+func (display Display) Aspect() float64 {
+	width, height := display.visible()
+	aspect := float64(width) / float64(height)
+	if display.PixelAspect {
+		aspect *= 8.0 / 7.0
+	}
+	return aspect
+}
+
+//Crop return the visible part of a frame, the frame can be wider than 256 (ntsc filter, scalers)
+func (display Display) Crop(bounds image.Rectangle) image.Rectangle {
+	sx := float64(bounds.Dx()) / nesWidth
+	sy := float64(bounds.Dy()) / nesHeight
+	width, height := display.visible()
+	x := bounds.Min.X + int(float64(display.Left)*sx)
+	y := bounds.Min.Y + int(float64(display.Top)*sy)
+	return image.Rect(x, y, x+int(float64(width)*sx), y+int(float64(height)*sy))
+}
+
+//TexCoords return the texture coordinates (left, top, right, bottom) of the visible part of a frame
+func (display Display) TexCoords() (float32, float32, float32, float32) {
+	width, height := display.visible()
+	left := float32(display.Left) / nesWidth
+	top := float32(display.Top) / nesHeight
+	return left, top, left + float32(width)/nesWidth, top + float32(height)/nesHeight
+}
+
+//Viewport return where the frame is drawn inside a window of the given size, in pixels
+func (display Display) Viewport(windowWidth int, windowHeight int) image.Rectangle {
+	if display.Stretch {
+		return image.Rect(0, 0, windowWidth, windowHeight)
+	}
+	_, height := display.visible()
+	aspect := display.Aspect()
+	scale := math.Min(float64(windowWidth)/(float64(height)*aspect), float64(windowHeight)/float64(height))
+	if display.IntegerScale && scale >= 1 {
+		scale = math.Floor(scale)
+	}
+	viewWidth := int(math.Round(float64(height) * aspect * scale))
+	viewHeight := int(math.Round(float64(height) * scale))
+	x := (windowWidth - viewWidth) / 2
+	y := (windowHeight - viewHeight) / 2
+	return image.Rect(x, y, x+viewWidth, y+viewHeight)
+}
+
+//ToFrame convert a position in a window of the given size to nes pixels, false when it is outside the picture
+func (display Display) ToFrame(x int, y int, windowWidth int, windowHeight int) (int, int, bool) {
+	viewport := display.Viewport(windowWidth, windowHeight)
+	if !(image.Point{x, y}).In(viewport) {
+		return -1, -1, false
+	}
+	width, height := display.visible()
+	frameX := display.Left + (x-viewport.Min.X)*width/viewport.Dx()
+	frameY := display.Top + (y-viewport.Min.Y)*height/viewport.Dy()
+	return frameX, frameY, true
+}
+
+//Apply crop a frame and resize it to its display aspect ratio, without losing resolution
+func (display Display) Apply(src image.Image) *image.RGBA {
+	crop := display.Crop(src.Bounds())
+	aspect := display.Aspect()
+	height := crop.Dy()
+	width := int(math.Round(float64(height) * aspect))
+	if crop.Dx() > width {
+		width = crop.Dx()
+		height = int(math.Round(float64(width) / aspect))
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		sy := crop.Min.Y + y*crop.Dy()/height
+		for x := 0; x < width; x++ {
+			sx := crop.Min.X + x*crop.Dx()/width
+			dst.Set(x, y, src.At(sx, sy))
+		}
+	}
+	return dst
+}
@@ -11,6 +11,13 @@ import (
 	"github.com/fogleman/nes/nes"
 )
 
+//a gif keeps one frame out of RecordFrameStep (20 frames per second) and at most MaxRecordedFrames of them (30 seconds),
+//the recording stops by itself when it is full
+const (
+	RecordFrameStep   = 3
+	MaxRecordedFrames = 600
+)
+
 func saveGIF(path string, frames []image.Image) error {
 	var palette []color.Color
 
@@ -18,10 +25,7 @@ func saveGIF(path string, frames []image.Image) error {
 		palette = append(palette, c)
 	}
 	g := gif.GIF{}
-	for i, src := range frames {
-		if i%3 != 0 {
-			continue
-		}
+	for _, src := range frames {
 		dst := image.NewPaletted(src.Bounds(), palette)
 		draw.Draw(dst, dst.Rect, src, image.ZP, draw.Src)
 		g.Image = append(g.Image, dst)
@@ -0,0 +1,166 @@
+package openglencapsulation
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+//a texture the passes of the chain render into
+type renderTarget struct {
+	framebuffer uint32
+	texture     uint32
+	width       int
+	height      int
+}
+
+//Renderer draws the frames with opengl 3.3 core: the frame is uploaded into a persistent texture,
+//then goes through the post-process passes, the last one draws the fullscreen quad in the window
+type Renderer struct {
+	vao           uint32
+	vbo           uint32
+	texture       uint32 // the nes frame
+	textureWidth  int
+	textureHeight int
+	passes        []*shaderPass
+	targets       []*renderTarget // one less than passes, the last pass draws in the window
+	frameCount    int
+}
+
+//NewRenderer create the renderer, shaderPaths are the glsl files of the post-process chain, applied in order
+func NewRenderer(shaderPaths []string) (*Renderer, error) {
+	var renderer Renderer
+
+	for _, path := range shaderPaths {
+		pass, err := loadShaderPass(path)
+		if err != nil {
+			renderer.Delete()
+			return nil, err
+		}
+		renderer.passes = append(renderer.passes, pass)
+	}
+	if len(renderer.passes) == 0 {
+		pass, err := newShaderPass(copyShader)
+		if err != nil {
+			return nil, err
+		}
+		renderer.passes = append(renderer.passes, pass)
+	}
+	for i := 1; i < len(renderer.passes); i++ {
+		renderer.targets = append(renderer.targets, &renderTarget{})
+	}
+	renderer.texture = CreateTexture()
+
+	// a fullscreen quad, drawn as a triangle strip
+	vertices := []float32{-1, -1, 1, -1, -1, 1, 1, 1}
+	gl.GenVertexArrays(1, &renderer.vao)
+	gl.BindVertexArray(renderer.vao)
+	gl.GenBuffers(1, &renderer.vbo)
+	gl.BindBuffer(gl.ARRAY_BUFFER, renderer.vbo)
+	gl.BufferData(gl.ARRAY_BUFFER, len(vertices)*4, gl.Ptr(vertices), gl.STATIC_DRAW)
+	gl.EnableVertexAttribArray(0)
+	gl.VertexAttribPointer(0, 2, gl.FLOAT, false, 0, gl.PtrOffset(0))
+	gl.BindVertexArray(0)
+	return &renderer, nil
+}
+
+//upload copy the frame into the persistent texture, the texture is reallocated only if the frame size changed
+func (renderer *Renderer) upload(frame *image.RGBA) {
+	width, height := frame.Rect.Dx(), frame.Rect.Dy()
+
+	gl.BindTexture(gl.TEXTURE_2D, renderer.texture)
+	gl.PixelStorei(gl.UNPACK_ALIGNMENT, 1)
+	if width != renderer.textureWidth || height != renderer.textureHeight {
+		AllocateTexture(width, height, nil)
+		renderer.textureWidth, renderer.textureHeight = width, height
+	}
+	SetTexture(frame)
+}
+
+//resize reallocate the texture of a render target if its size changed
+func (target *renderTarget) resize(width int, height int) error {
+	if target.width == width && target.height == height {
+		return nil
+	}
+	if target.framebuffer == 0 {
+		gl.GenFramebuffers(1, &target.framebuffer)
+		target.texture = CreateTexture()
+	}
+	gl.BindTexture(gl.TEXTURE_2D, target.texture)
+	AllocateTexture(width, height, nil)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, target.framebuffer)
+	gl.FramebufferTexture2D(gl.FRAMEBUFFER, gl.COLOR_ATTACHMENT0, gl.TEXTURE_2D, target.texture, 0)
+	status := gl.CheckFramebufferStatus(gl.FRAMEBUFFER)
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+	if status != gl.FRAMEBUFFER_COMPLETE {
+		return fmt.Errorf("incomplete framebuffer: 0x%X", status)
+	}
+	target.width, target.height = width, height
+	return nil
+}
+
+//Draw display a frame in a window of the given size, cropped and placed as the display says
+func (renderer *Renderer) Draw(frame *image.RGBA, display Display, windowWidth int, windowHeight int) error {
+	viewport := display.Viewport(windowWidth, windowHeight)
+	if viewport.Dx() <= 0 || viewport.Dy() <= 0 {
+		return nil
+	}
+	renderer.upload(frame)
+	left, top, right, bottom := display.TexCoords()
+	texRect := [4]float32{left, top, right, bottom}
+	source := renderer.texture
+	sourceWidth, sourceHeight := renderer.textureWidth, renderer.textureHeight
+
+	gl.BindVertexArray(renderer.vao)
+	gl.ActiveTexture(gl.TEXTURE0)
+	for i, pass := range renderer.passes {
+		if i < len(renderer.targets) {
+			target := renderer.targets[i]
+			if err := target.resize(viewport.Dx(), viewport.Dy()); err != nil {
+				return err
+			}
+			gl.BindFramebuffer(gl.FRAMEBUFFER, target.framebuffer)
+			gl.Viewport(0, 0, int32(target.width), int32(target.height))
+		} else {
+			gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+			// opengl puts the origin at the bottom left of the window
+			gl.Viewport(int32(viewport.Min.X), int32(windowHeight-viewport.Max.Y), int32(viewport.Dx()), int32(viewport.Dy()))
+		}
+		gl.BindTexture(gl.TEXTURE_2D, source)
+		pass.use(texRect, sourceWidth, sourceHeight, viewport.Dx(), viewport.Dy(), renderer.frameCount)
+		gl.DrawArrays(gl.TRIANGLE_STRIP, 0, 4)
+		if i < len(renderer.targets) {
+			// the render targets are upside down compared to the frame
+			source = renderer.targets[i].texture
+			sourceWidth, sourceHeight = viewport.Dx(), viewport.Dy()
+			texRect = [4]float32{0, 1, 1, 0}
+		}
+	}
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+	gl.BindVertexArray(0)
+	gl.UseProgram(0)
+	gl.Viewport(0, 0, int32(windowWidth), int32(windowHeight))
+	renderer.frameCount++
+	return nil
+}
+
+//Delete free the opengl objects of the renderer
+func (renderer *Renderer) Delete() {
+	for _, pass := range renderer.passes {
+		pass.delete()
+	}
+	for _, target := range renderer.targets {
+		if target.framebuffer != 0 {
+			gl.DeleteFramebuffers(1, &target.framebuffer)
+			gl.DeleteTextures(1, &target.texture)
+		}
+	}
+	if renderer.texture != 0 {
+		gl.DeleteTextures(1, &renderer.texture)
+	}
+	if renderer.vao != 0 {
+		gl.DeleteBuffers(1, &renderer.vbo)
+		gl.DeleteVertexArrays(1, &renderer.vao)
+	}
+}
@@ -1,13 +1,29 @@
 package openglencapsulation
 
 import (
+	"fmt"
 	"image"
-	"image/draw"
+	"image/png"
+	"os"
 )
 
-//copy screen and return it
-func TakeScreenShot(src image.Image) *image.RGBA {
-	dst := image.NewRGBA(src.Bounds())
-	draw.Draw(dst, dst.Rect, src, image.ZP, draw.Src)
-	return dst
+//copy screen, cropped and shaped like it is displayed, and return it
+func TakeScreenShot(src image.Image, display Display) *image.RGBA {
+	return display.Apply(src)
+}
+
+//save a screenshot in the first free screenshotXXX.png file
+func SaveScreenShot(screenShot image.Image) error {
+	for i := 0; i < 1000; i++ {
+		path := fmt.Sprintf("screenshot%03d.png", i)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			return png.Encode(file, screenShot)
+		}
+	}
+	return fmt.Errorf("too many screenshots")
 }
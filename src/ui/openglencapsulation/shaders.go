@@ -0,0 +1,136 @@
+package openglencapsulation
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+)
+
+//vertexShader draws the fullscreen quad, TexRect is the part of the source texture (left, top, right, bottom) to show
+const vertexShader = `#version 330 core
+layout(location = 0) in vec2 Position;
+uniform vec4 TexRect;
+out vec2 TexCoord;
+void main() {
+	vec2 uv = Position * 0.5 + 0.5;
+	TexCoord = vec2(mix(TexRect.x, TexRect.z, uv.x), mix(TexRect.w, TexRect.y, uv.y));
+	gl_Position = vec4(Position, 0.0, 1.0);
+}
+` + "\x00"
+
+//copyShader only displays the source, it is used when the user gave no post-process pass
+const copyShader = `#version 330 core
+in vec2 TexCoord;
+out vec4 FragColor;
+uniform sampler2D Source;
+void main() {
+	FragColor = texture(Source, TexCoord);
+}
+`
+
+//a post-process pass, its fragment shader can use:
+//  in vec2 TexCoord; uniform sampler2D Source;
+//  uniform vec4 SourceSize; (width, height, 1/width, 1/height of the source texture)
+//  uniform vec4 OutputSize; (same for the output) uniform int FrameCount;
+//a "#pragma filter linear" line makes the source sampled with bilinear filtering instead of nearest
+type shaderPass struct {
+	program    uint32
+	filter     int32
+	source     int32 // uniforms locations
+	sourceSize int32
+	outputSize int32
+	frameCount int32
+	texRect    int32
+}
+
+//compileShader compile a shader source, the source must end with a \x00
+func compileShader(source string, shaderType uint32) (uint32, error) {
+	shader := gl.CreateShader(shaderType)
+	csources, free := gl.Strs(source)
+	gl.ShaderSource(shader, 1, csources, nil)
+	free()
+	gl.CompileShader(shader)
+
+	var status int32
+	gl.GetShaderiv(shader, gl.COMPILE_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetShaderiv(shader, gl.INFO_LOG_LENGTH, &logLength)
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetShaderInfoLog(shader, logLength, nil, gl.Str(log))
+		gl.DeleteShader(shader)
+		return 0, fmt.Errorf("failed to compile shader: %v", log)
+	}
+	return shader, nil
+}
+
+//newShaderPass compile and link a fragment shader with the quad vertex shader
+func newShaderPass(fragmentSource string) (*shaderPass, error) {
+	vertex, err := compileShader(vertexShader, gl.VERTEX_SHADER)
+	if err != nil {
+		return nil, err
+	}
+	defer gl.DeleteShader(vertex)
+	fragment, err := compileShader(fragmentSource+"\x00", gl.FRAGMENT_SHADER)
+	if err != nil {
+		return nil, err
+	}
+	defer gl.DeleteShader(fragment)
+
+	var pass shaderPass
+	pass.program = gl.CreateProgram()
+	gl.AttachShader(pass.program, vertex)
+	gl.AttachShader(pass.program, fragment)
+	gl.LinkProgram(pass.program)
+
+	var status int32
+	gl.GetProgramiv(pass.program, gl.LINK_STATUS, &status)
+	if status == gl.FALSE {
+		var logLength int32
+		gl.GetProgramiv(pass.program, gl.INFO_LOG_LENGTH, &logLength)
+		log := strings.Repeat("\x00", int(logLength+1))
+		gl.GetProgramInfoLog(pass.program, logLength, nil, gl.Str(log))
+		gl.DeleteProgram(pass.program)
+		return nil, fmt.Errorf("failed to link program: %v", log)
+	}
+	pass.filter = gl.NEAREST
+	if strings.Contains(fragmentSource, "#pragma filter linear") {
+		pass.filter = gl.LINEAR
+	}
+	pass.source = gl.GetUniformLocation(pass.program, gl.Str("Source\x00"))
+	pass.sourceSize = gl.GetUniformLocation(pass.program, gl.Str("SourceSize\x00"))
+	pass.outputSize = gl.GetUniformLocation(pass.program, gl.Str("OutputSize\x00"))
+	pass.frameCount = gl.GetUniformLocation(pass.program, gl.Str("FrameCount\x00"))
+	pass.texRect = gl.GetUniformLocation(pass.program, gl.Str("TexRect\x00"))
+	return &pass, nil
+}
+
+//loadShaderPass load a post-process pass from a glsl fragment shader file
+func loadShaderPass(path string) (*shaderPass, error) {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pass, err := newShaderPass(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return pass, nil
+}
+
+//use bind the program of the pass and set its uniforms
+func (pass *shaderPass) use(texRect [4]float32, sourceWidth, sourceHeight, outputWidth, outputHeight int, frameCount int) {
+	gl.UseProgram(pass.program)
+	gl.Uniform1i(pass.source, 0)
+	gl.Uniform4f(pass.texRect, texRect[0], texRect[1], texRect[2], texRect[3])
+	gl.Uniform4f(pass.sourceSize, float32(sourceWidth), float32(sourceHeight), 1/float32(sourceWidth), 1/float32(sourceHeight))
+	gl.Uniform4f(pass.outputSize, float32(outputWidth), float32(outputHeight), 1/float32(outputWidth), 1/float32(outputHeight))
+	gl.Uniform1i(pass.frameCount, int32(frameCount))
+	SetTextureFilter(pass.filter)
+}
+
+func (pass *shaderPass) delete() {
+	gl.DeleteProgram(pass.program)
+}
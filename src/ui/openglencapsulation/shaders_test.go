@@ -0,0 +1,76 @@
+package openglencapsulation
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-gl/gl/v3.3-core/gl"
+	"github.com/go-gl/glfw/v3.3/glfw"
+)
+
+//the shaders given with the emulator
+const shadersPath = "../../../assets/shaders"
+
+//openContext make an opengl 3.3 core context current in a hidden window, like the one of the emulator
+func openContext(t *testing.T) func() {
+	runtime.LockOSThread()
+	if err := glfw.Init(); err != nil {
+		runtime.UnlockOSThread()
+		t.Skipf("no opengl context: %v", err)
+	}
+	glfw.WindowHint(glfw.Visible, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 3)
+	glfw.WindowHint(glfw.ContextVersionMinor, 3)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	window, err := glfw.CreateWindow(64, 64, "shaders test", nil, nil)
+	if err == nil {
+		window.MakeContextCurrent()
+		err = gl.Init()
+	}
+	if err != nil {
+		glfw.Terminate()
+		runtime.UnlockOSThread()
+		t.Skipf("no opengl context: %v", err)
+	}
+	return func() {
+		window.Destroy()
+		glfw.Terminate()
+		runtime.UnlockOSThread()
+	}
+}
+
+//TestShadersLink every shader of the assets directory compiles and links with the quad vertex shader
+func TestShadersLink(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join(shadersPath, "*.glsl"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no shader in %s: %v", shadersPath, err)
+	}
+	defer openContext(t)()
+
+	pass, err := newShaderPass(copyShader)
+	if err != nil {
+		t.Fatalf("copy shader: %v", err)
+	}
+	pass.delete()
+	for _, path := range paths {
+		pass, err := loadShaderPass(path)
+		if err != nil {
+			t.Errorf("%v", err)
+			continue
+		}
+		var status int32
+		gl.GetProgramiv(pass.program, gl.LINK_STATUS, &status)
+		if status != gl.TRUE {
+			t.Errorf("%s: the program is not linked", path)
+		}
+		source, _ := ioutil.ReadFile(path)
+		if linear := strings.Contains(string(source), "#pragma filter linear"); linear != (pass.filter == gl.LINEAR) {
+			t.Errorf("%s: linear filtering %v, the shader asks for %v", path, pass.filter == gl.LINEAR, linear)
+		}
+		pass.delete()
+	}
+}
@@ -2,8 +2,9 @@ package openglencapsulation
 
 import (
 	"image"
+	"unsafe"
 
-	"github.com/go-gl/gl/v2.1/gl"
+	"github.com/go-gl/gl/v3.3-core/gl"
 )
 
 //create a texture
@@ -20,7 +21,23 @@ func CreateTexture() uint32 {
 	return texture
 }
 
-//set a two-dimensional texture image
+//allocate the storage of the bound texture, pixels can be nil
+func AllocateTexture(width int, height int, pixels []byte) {
+	var data unsafe.Pointer
+
+	if pixels != nil {
+		data = gl.Ptr(pixels)
+	}
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA8, int32(width), int32(height), 0, gl.RGBA, gl.UNSIGNED_BYTE, data)
+}
+
+//set a two-dimensional texture image, the bound texture must already have the size of the image
 func SetTexture(image *image.RGBA) {
-	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGBA, int32(image.Rect.Size().X), int32(image.Rect.Size().Y), 0, gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(image.Pix))
+	gl.TexSubImage2D(gl.TEXTURE_2D, 0, 0, 0, int32(image.Rect.Size().X), int32(image.Rect.Size().Y), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(image.Pix))
+}
+
+//choose how the bound texture is sampled: nearest or linear
+func SetTextureFilter(filter int32) {
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, filter)
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, filter)
 }
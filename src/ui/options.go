@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	oglEncap "./openglencapsulation"
+	"github.com/hadi-ilies/MyNesEmulator/src/nes/nescomponents"
+)
+
+//Options are the settings given by the user on the command line
+type Options struct {
+	Palette     string                          // "" for the built-in palette, "ntsc" for a generated one or the path of a .pal file
+	NtscPalette nescomponents.NtscPaletteParams // params of the generated palette and of the ntsc filter
+	Ntsc        string                          // ntsc filter: "" (none), "composite", "svideo" or "rgb"
+	Display     oglEncap.Display                // overscan and shape of the picture, on screen and in screenshots
+	Shaders     []string                        // glsl files of the post-process passes, applied in order
+	Scaler      string                          // software scaler: "" (none), "scale2x", "edge3x", "xbr4x"... (see scalers.Names)
+	Multitap    string                          // four players adapter: "none", "fourscore" or "hori", "" to use the game header
+	Port2       string                          // device plugged in port 2: "controller", "zapper", "arkanoid" or "powerpad"
+	Expansion   string                          // famicom expansion device: "none", "arkanoid", "trainer" or "keyboard"
+	Config      string                          // input config file, "" for the one in the user config dir
+	FastForward float64                         // speed of the fast forward, 0 for as fast as possible
+	Vsync       bool                            // wait for the monitor refresh to show a frame
+	Patch       string                          // IPS, UPS or BPS patch applied to the rom, "" for the one next to the rom if any
+	FdsBios     string                          // BIOS of the Famicom Disk System, "" for disksys.rom next to the disk image
+}
+
+//NewOptions return the default settings
+func NewOptions() Options {
+	var options Options
+
+	options.NtscPalette = nescomponents.DefaultNtscPaletteParams()
+	options.Display = oglEncap.NewDisplay()
+	options.Vsync = true
+	return options
+}
+
+//SetOverscan parse the overscan given as "top,bottom,left,right"
+func (options *Options) SetOverscan(value string) error {
+	var display = &options.Display
+
+	if _, err := fmt.Sscanf(value, "%d,%d,%d,%d", &display.Top, &display.Bottom, &display.Left, &display.Right); err != nil {
+		return fmt.Errorf("overscan must be top,bottom,left,right: %v", err)
+	}
+	if display.Top < 0 || display.Bottom < 0 || display.Left < 0 || display.Right < 0 ||
+		display.Top+display.Bottom >= 240 || display.Left+display.Right >= 256 {
+		return fmt.Errorf("overscan %s crops the whole picture", value)
+	}
+	return nil
+}
+
+//SetShaders parse the comma separated list of post-process shader files
+func (options *Options) SetShaders(value string) error {
+	options.Shaders = nil
+	for _, path := range strings.Split(value, ",") {
+		if path != "" {
+			options.Shaders = append(options.Shaders, path)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package ui
+
+import (
+	"github.com/hadi-ilies/MyNesEmulator/src/nes/nescomponents"
+)
+
+//palettes the user can switch between with the P key
+const (
+	paletteDefault = iota // built-in colors
+	paletteNtsc           // generated from the ntsc signal
+	paletteFile           // loaded from the .pal file given by the user
+)
+
+//paletteFromOptions return the palette chosen on the command line
+func paletteFromOptions(options Options) int {
+	switch options.Palette {
+	case "":
+		return paletteDefault
+	case "ntsc":
+		return paletteNtsc
+	}
+	return paletteFile
+}
+
+//usePalette replace the colors used by the ppu
+func usePalette(palette int, options Options) error {
+	switch palette {
+	case paletteNtsc:
+		nescomponents.UseNtscPalette(options.NtscPalette)
+	case paletteFile:
+		return nescomponents.LoadPaletteFile(options.Palette)
+	default:
+		nescomponents.UseDefaultPalette()
+	}
+	return nil
+}
+
+//nextPalette return the palette that follows the current one, the file one is skipped if the user gave none
+func nextPalette(palette int, options Options) int {
+	palette = (palette + 1) % (paletteFile + 1)
+	if palette == paletteFile && paletteFromOptions(options) != paletteFile {
+		palette = paletteDefault
+	}
+	return palette
+}
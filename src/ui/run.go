@@ -1,11 +1,16 @@
 package ui
 
 import (
+	"fmt"
 	"runtime"
+	"strings"
 
-	"github.com/go-gl/gl/v2.1/gl"
+	oglEncap "./openglencapsulation"
+	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/hadi-ilies/MyNesEmulator/src/constant"
+	"github.com/hadi-ilies/MyNesEmulator/src/nes"
+	"github.com/hadi-ilies/MyNesEmulator/src/nes/nescomponents"
 )
 
 func init() {
@@ -20,25 +25,91 @@ func initOpengl() bool {
 	if err := gl.Init(); err != nil {
 		return false
 	}
-	gl.Enable(gl.TEXTURE_2D)
 	return true
 }
 
 //init whole emulator and start it
-func Start(gamePath string) bool {
+func Start(gamePath string, options Options) bool {
 
-	err := glfw.Init()
+	if err := usePalette(paletteFromOptions(options), options); err != nil {
+		println(err.Error())
+		return false
+	}
+	if _, err := newNtscFilter(options.Ntsc, options); err != nil {
+		println(err.Error())
+		return false
+	}
+	if _, err := newScaler(options.Scaler); err != nil {
+		println(err.Error())
+		return false
+	}
+	if err := checkInput(options); err != nil {
+		println(err.Error())
+		return false
+	}
+	if options.FastForward < 0 {
+		println("the fast forward speed can not be negative")
+		return false
+	}
+	config, err := LoadConfig(options.Config)
+	if err != nil {
+		println(err.Error())
+		return false
+	}
+	controls, err := NewControls(config)
+	if err != nil {
+		println(err.Error())
+		return false
+	}
+	// the game is loaded before the window opens, a rom or a patch that cannot be loaded stops here
+	console, err := nes.NewNes(gamePath, nescomponents.LoadOptions{Patch: options.Patch, FdsBios: options.FdsBios})
+	if err != nil {
+		println(err.Error())
+		return false
+	}
+	err = glfw.Init()
 	if err != nil {
 		return false
 	}
 	defer glfw.Terminate() //destroy all opengl stuff when func is terminated
 	//create the ui
-	ui := NewUI(constant.WindowWidth*constant.Scale, constant.WindowHeight*constant.Scale, constant.UITitle)
+	ui := NewUI(constant.WindowWidth*constant.Scale, constant.WindowHeight*constant.Scale, constant.UITitle, options)
+	ui.controls = controls
+	glfw.SetJoystickCallback(onJoystick)
 
 	if !initOpengl() {
 		return false
 	}
+	ui.renderer, err = oglEncap.NewRenderer(options.Shaders)
+	if err != nil {
+		println(err.Error())
+		return false
+	}
+	defer ui.renderer.Delete()
+	ui.audio, err = NewAudio()
+	if err != nil {
+		println("no sound:", err.Error())
+	} else {
+		defer ui.audio.Close()
+	}
 
-	ui.Run(gamePath)
+	ui.Run(&console)
 	return true
 }
+
+//PrintMappers list the mappers the emulator supports, with their boards and expansion audio
+func PrintMappers() {
+	for _, mapper := range nescomponents.SupportedMappers() {
+		line := fmt.Sprintf("%03d %s", mapper.Number, mapper.Name)
+		if len(mapper.Submappers) != 0 {
+			line += fmt.Sprintf(" (submappers %v)", mapper.Submappers)
+		}
+		if len(mapper.Boards) != 0 {
+			line += ": " + strings.Join(mapper.Boards, ", ")
+		}
+		if mapper.Audio != "" {
+			line += ", " + mapper.Audio + " audio"
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,104 @@
+package scalers
+
+import "image"
+
+// edge2x, edge3x and edge4x: an edge-aware corner blending scaler
+// two colors are different when their luma or chroma differ more than the thresholds of hqx (Maxim Stepin),
+// each corner of a pixel is blended with the neighbours that touch it:
+// - the two sides are alike but differ from the pixel: an edge crosses the corner, the corner takes the sides color
+// - otherwise the corner is only smoothed with the neighbours that are alike
+// the further a sub pixel is from the center, the more it is blended, which gives the 2x, 3x and 4x versions.
+// it is not hqx: there are no lookup tables, the same rules are used for every factor
+
+// thresholds of hqx
+const (
+	edgeYThreshold = 48
+	edgeUThreshold = 7
+	edgeVThreshold = 6
+)
+
+//edgeDifferent tell whether two colors are different
+func edgeDifferent(c1 uint32, c2 uint32) bool {
+	if c1 == c2 {
+		return false
+	}
+	y1, u1, v1 := yuv(c1)
+	y2, u2, v2 := yuv(c2)
+	return abs(y1-y2) > edgeYThreshold || abs(u1-u2) > edgeUThreshold || abs(v1-v2) > edgeVThreshold
+}
+
+//edgeCorner find how the sub pixels near a corner are blended, side1/side2 are the neighbours sharing an edge with
+//the pixel at that corner, diagonal the one sharing only the corner.
+//a sub pixel at a distance d (0: center, 1: corner) is blended with the returned color by min(d * weight, max)
+func edgeCorner(e uint32, side1 uint32, side2 uint32, diagonal uint32) (uint32, float64, float64) {
+	d1 := edgeDifferent(e, side1)
+	d2 := edgeDifferent(e, side2)
+	dd := edgeDifferent(e, diagonal)
+	sides := mix(side1, side2, 0.5)
+
+	switch {
+	case d1 && d2 && !edgeDifferent(side1, side2):
+		// an edge crosses the corner, weaker for a 1 pixel wide diagonal line
+		if !dd {
+			return sides, 1, 0.75
+		}
+		return sides, 1.5, 0.75
+	case d1 && d2:
+		// surrounded by different colors: only a little of the diagonal
+		if dd {
+			return e, 0, 0
+		}
+		return diagonal, 0.25, 1
+	case d1:
+		// only smooth with the side that is alike
+		return side2, 0.25, 1
+	case d2:
+		return side1, 0.25, 1
+	}
+	// all alike: smooth gradients
+	return sides, 0.5, 1
+}
+
+func edgenx(src *image.RGBA, dst *image.RGBA, factor int, top int, bottom int) {
+	var colors [4]uint32
+	var weights, maxWeights [4]float64
+
+	for y := top; y < bottom; y++ {
+		for x := 0; x < src.Rect.Dx(); x++ {
+			e := pixelAt(src, x, y)
+			// corners: 0 top left, 1 top right, 2 bottom left, 3 bottom right
+			for corner := 0; corner < 4; corner++ {
+				dx, dy := corner%2*2-1, corner/2*2-1
+				colors[corner], weights[corner], maxWeights[corner] =
+					edgeCorner(e, pixelAt(src, x+dx, y), pixelAt(src, x, y+dy), pixelAt(src, x+dx, y+dy))
+			}
+			for sy := 0; sy < factor; sy++ {
+				for sx := 0; sx < factor; sx++ {
+					// sub pixel center relative to the pixel center, from -0.5 to 0.5
+					u := (float64(sx)+0.5)/float64(factor) - 0.5
+					v := (float64(sy)+0.5)/float64(factor) - 0.5
+					corner := 0
+					if u > 0 {
+						corner++
+					} else {
+						u = -u
+					}
+					if v > 0 {
+						corner += 2
+					} else {
+						v = -v
+					}
+					weight := (u + v) * weights[corner]
+					if weight > maxWeights[corner] {
+						weight = maxWeights[corner]
+					}
+					color := e
+					if weight > 0 {
+						color = mix(e, colors[corner], weight)
+					}
+					setPixel(dst, x*factor+sx, y*factor+sy, color)
+				}
+			}
+		}
+	}
+}
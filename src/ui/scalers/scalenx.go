@@ -0,0 +1,81 @@
+package scalers
+
+import "image"
+
+// Scale2x and Scale3x (AdvMAME2x/3x) only copy neighbours, they never create new colors
+// http://www.scale2x.it/algorithm
+
+//   A
+// C P B
+//   D
+func scale2x(src *image.RGBA, dst *image.RGBA, factor int, top int, bottom int) {
+	for y := top; y < bottom; y++ {
+		for x := 0; x < src.Rect.Dx(); x++ {
+			p := pixelAt(src, x, y)
+			a := pixelAt(src, x, y-1)
+			b := pixelAt(src, x+1, y)
+			c := pixelAt(src, x-1, y)
+			d := pixelAt(src, x, y+1)
+			e0, e1, e2, e3 := p, p, p, p
+			if c == a && c != d && a != b {
+				e0 = a
+			}
+			if a == b && a != c && b != d {
+				e1 = b
+			}
+			if d == c && d != b && c != a {
+				e2 = c
+			}
+			if b == d && b != a && d != c {
+				e3 = d
+			}
+			setPixel(dst, x*2, y*2, e0)
+			setPixel(dst, x*2+1, y*2, e1)
+			setPixel(dst, x*2, y*2+1, e2)
+			setPixel(dst, x*2+1, y*2+1, e3)
+		}
+	}
+}
+
+// A B C
+// D E F
+// G H I
+func scale3x(src *image.RGBA, dst *image.RGBA, factor int, top int, bottom int) {
+	for y := top; y < bottom; y++ {
+		for x := 0; x < src.Rect.Dx(); x++ {
+			a, b, c := pixelAt(src, x-1, y-1), pixelAt(src, x, y-1), pixelAt(src, x+1, y-1)
+			d, e, f := pixelAt(src, x-1, y), pixelAt(src, x, y), pixelAt(src, x+1, y)
+			g, h, i := pixelAt(src, x-1, y+1), pixelAt(src, x, y+1), pixelAt(src, x+1, y+1)
+			out := [9]uint32{e, e, e, e, e, e, e, e, e}
+			if b != h && d != f {
+				if d == b {
+					out[0] = d
+				}
+				if (d == b && e != c) || (b == f && e != a) {
+					out[1] = b
+				}
+				if b == f {
+					out[2] = f
+				}
+				if (d == b && e != g) || (d == h && e != a) {
+					out[3] = d
+				}
+				if (b == f && e != i) || (h == f && e != c) {
+					out[5] = f
+				}
+				if d == h {
+					out[6] = d
+				}
+				if (d == h && e != i) || (h == f && e != g) {
+					out[7] = h
+				}
+				if h == f {
+					out[8] = f
+				}
+			}
+			for j, color := range out {
+				setPixel(dst, x*3+j%3, y*3+j/3, color)
+			}
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package scalers
+
+import (
+	"fmt"
+	"image"
+	"runtime"
+	"sync"
+)
+
+//Scaler enlarge the nes frames on the cpu, it keeps its output image between two frames
+type Scaler struct {
+	Name   string
+	Factor int
+	scale  func(src *image.RGBA, dst *image.RGBA, factor int, top int, bottom int) // scale the rows top to bottom-1
+	output *image.RGBA
+}
+
+//Names of the scalers, in the order the user switch between them
+var Names = []string{"scale2x", "scale3x", "edge2x", "edge3x", "edge4x", "xbr2x", "xbr3x", "xbr4x"}
+
+//New create a scaler from its name
+func New(name string) (*Scaler, error) {
+	switch name {
+	case "scale2x":
+		return &Scaler{Name: name, Factor: 2, scale: scale2x}, nil
+	case "scale3x":
+		return &Scaler{Name: name, Factor: 3, scale: scale3x}, nil
+	case "edge2x", "edge3x", "edge4x":
+		return &Scaler{Name: name, Factor: int(name[4] - '0'), scale: edgenx}, nil
+	case "xbr2x", "xbr3x", "xbr4x":
+		return &Scaler{Name: name, Factor: int(name[3] - '0'), scale: xbr}, nil
+	case "hq2x", "hq3x", "hq4x": // left out, they need the lookup tables of hqx checked against its reference code
+		return nil, fmt.Errorf("%s is not provided, edge%s is the closest scaler", name, name[2:])
+	}
+	return nil, fmt.Errorf("unknown scaler: %s", name)
+}
+
+//Apply enlarge a frame, the returned image is reused by the next call
+func (scaler *Scaler) Apply(src *image.RGBA) *image.RGBA {
+	width := src.Rect.Dx() * scaler.Factor
+	height := src.Rect.Dy() * scaler.Factor
+
+	if scaler.output == nil || scaler.output.Rect.Dx() != width || scaler.output.Rect.Dy() != height {
+		scaler.output = image.NewRGBA(image.Rect(0, 0, width, height))
+	}
+	// every cpu scales its own band of rows
+	var wg sync.WaitGroup
+	bands := runtime.NumCPU()
+	rows := src.Rect.Dy()
+	for band := 0; band < bands; band++ {
+		top, bottom := rows*band/bands, rows*(band+1)/bands
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			scaler.scale(src, scaler.output, scaler.Factor, top, bottom)
+		}()
+	}
+	wg.Wait()
+	return scaler.output
+}
+
+/** pixel helpers, the colors are packed as 0xRRGGBB **/
+
+//pixelAt return a pixel of the source, the coordinates are clamped to the edges
+func pixelAt(src *image.RGBA, x int, y int) uint32 {
+	if x < 0 {
+		x = 0
+	} else if x >= src.Rect.Dx() {
+		x = src.Rect.Dx() - 1
+	}
+	if y < 0 {
+		y = 0
+	} else if y >= src.Rect.Dy() {
+		y = src.Rect.Dy() - 1
+	}
+	i := y*src.Stride + x*4
+	return uint32(src.Pix[i])<<16 | uint32(src.Pix[i+1])<<8 | uint32(src.Pix[i+2])
+}
+
+func setPixel(dst *image.RGBA, x int, y int, c uint32) {
+	i := y*dst.Stride + x*4
+	dst.Pix[i] = byte(c >> 16)
+	dst.Pix[i+1] = byte(c >> 8)
+	dst.Pix[i+2] = byte(c)
+	dst.Pix[i+3] = 0xFF
+}
+
+//mix blend two colors, w is the weight of the second one (0 to 1)
+func mix(c1 uint32, c2 uint32, w float64) uint32 {
+	w2 := uint32(w*256 + 0.5)
+	w1 := 256 - w2
+	// red and blue are blended together, green apart
+	rb := ((c1&0xFF00FF)*w1 + (c2&0xFF00FF)*w2 + 0x800080) >> 8 & 0xFF00FF
+	g := ((c1&0x00FF00)*w1 + (c2&0x00FF00)*w2 + 0x008000) >> 8 & 0x00FF00
+	return rb | g
+}
+
+//yuv convert a color to the luma/chroma space the scalers compare colors in
+func yuv(c uint32) (int, int, int) {
+	r := int((c >> 16) & 0xFF)
+	g := int((c >> 8) & 0xFF)
+	b := int(c & 0xFF)
+	y := (r*306 + g*601 + b*117) >> 10
+	u := (-r*173-g*339+b*512)>>10 + 128
+	v := (r*512-g*429-b*83)>>10 + 128
+	return y, u, v
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,138 @@
+package scalers
+
+import (
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+//go test -update rewrites the golden images after an intended change of a scaler
+var update = flag.Bool("update", false, "rewrite the golden images of testdata")
+
+//testFrame draw a fixed frame with what the scalers must handle: flat areas, 45 degree, shallow and steep lines,
+//1 pixel wide lines, a dithered area, a gradient and a few letters
+func testFrame() *image.RGBA {
+	frame := image.NewRGBA(image.Rect(0, 0, 48, 32))
+	sky := color.RGBA{0x5C, 0x94, 0xFC, 0xFF}
+	black := color.RGBA{0, 0, 0, 0xFF}
+	white := color.RGBA{0xFC, 0xFC, 0xFC, 0xFF}
+	red := color.RGBA{0xB8, 0x1C, 0x0C, 0xFF}
+
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 48; x++ {
+			frame.SetRGBA(x, y, sky)
+		}
+	}
+	for i := 0; i < 16; i++ {
+		frame.SetRGBA(i, 15-i, black)   // 45 degrees
+		frame.SetRGBA(16+i, 8+i/2, red) // shallow
+		frame.SetRGBA(32+i/2, i, white) // steep
+	}
+	for y := 16; y < 32; y++ {
+		for x := 0; x < 16; x++ {
+			if (x+y)%2 == 0 {
+				frame.SetRGBA(x, y, red) // dithering
+			}
+			frame.SetRGBA(16+x, y, color.RGBA{byte(x * 16), byte(y * 8), 0x80, 0xFF}) // gradient
+		}
+	}
+	// "HI" in a 3x5 font
+	glyphs := []string{"X.X.XXX", "X.X..X.", "XXX..X.", "X.X..X.", "X.X.XXX"}
+	for y, row := range glyphs {
+		for x, c := range row {
+			if c == 'X' {
+				frame.SetRGBA(36+x, 20+y, black)
+			}
+		}
+	}
+	return frame
+}
+
+func TestScalersGolden(t *testing.T) {
+	frame := testFrame()
+
+	for _, name := range Names {
+		scaler, err := New(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output := scaler.Apply(frame)
+		if output.Rect.Dx() != 48*scaler.Factor || output.Rect.Dy() != 32*scaler.Factor {
+			t.Errorf("%s: output of %v", name, output.Rect)
+			continue
+		}
+		path := filepath.Join("testdata", name+".png")
+		if *update {
+			if err := writePng(path, output); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		golden, err := readPng(path)
+		if err != nil {
+			t.Fatalf("%s: %v (go test -update writes the golden images)", name, err)
+		}
+		if x, y, ok := samePixels(output, golden); !ok {
+			t.Errorf("%s: differs from %s at %d,%d", name, path, x, y)
+		}
+	}
+}
+
+//TestScalersFlat an image of one color must stay of that color, the scalers blend nothing without an edge
+func TestScalersFlat(t *testing.T) {
+	frame := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := 0; i < len(frame.Pix); i += 4 {
+		copy(frame.Pix[i:], []byte{0x5C, 0x94, 0xFC, 0xFF})
+	}
+	for _, name := range Names {
+		scaler, _ := New(name)
+		output := scaler.Apply(frame)
+		for i := 0; i < len(output.Pix); i += 4 {
+			if output.Pix[i] != 0x5C || output.Pix[i+1] != 0x94 || output.Pix[i+2] != 0xFC {
+				t.Errorf("%s: flat area changed at pixel %d", name, i/4)
+				break
+			}
+		}
+	}
+}
+
+func samePixels(a *image.RGBA, b image.Image) (int, int, bool) {
+	if a.Rect != b.Bounds() {
+		return 0, 0, false
+	}
+	for y := a.Rect.Min.Y; y < a.Rect.Max.Y; y++ {
+		for x := a.Rect.Min.X; x < a.Rect.Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return x, y, false
+			}
+		}
+	}
+	return 0, 0, true
+}
+
+func readPng(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return png.Decode(file)
+}
+
+func writePng(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return png.Encode(file, img)
+}
@@ -0,0 +1,173 @@
+package scalers
+
+import (
+	"image"
+	"sync"
+)
+
+// xBR (scale by rules) by Hyllian, level 2 rules
+// every corner of a pixel is checked for an edge, the shape of the edge (45 degrees, shallow, steep)
+// says which part of the enlarged pixel takes the color of the other side of the edge.
+// the weights of the 2x version are the area each sub pixel covers behind the edge line,
+// which gives the same shapes at 3x and 4x
+
+//edge shapes found at a corner
+const (
+	xbrEdge   = iota // 45 degrees
+	xbrLeft          // shallow, goes on toward the left
+	xbrUp            // steep, goes on toward the top
+	xbrLeftUp        // both
+	xbrNbShapes
+)
+
+//xbrCoverages[shape][rotation][sub pixel] area of each sub pixel behind the edge line, computed once per factor
+type xbrCoverages [xbrNbShapes][4][]float64
+
+var (
+	xbrCoveragesMutex   sync.Mutex
+	xbrCoveragesByScale = map[int]*xbrCoverages{}
+)
+
+//xbrInside tell if a point of a pixel (corner at 1, 1) is behind an edge
+func xbrInside(shape int, x float64, y float64) bool {
+	switch shape {
+	case xbrLeft:
+		return y > 1-x/2
+	case xbrUp:
+		return x > 1-y/2
+	case xbrLeftUp:
+		return y > 1-x/2 || x > 1-y/2
+	}
+	return x+y > 1.5
+}
+
+//xbrRotate turns an offset a quarter clockwise as many times as asked, rotation 0 is the bottom right corner
+func xbrRotate(dx int, dy int, rotation int) (int, int) {
+	for i := 0; i < rotation; i++ {
+		dx, dy = dy, -dx
+	}
+	return dx, dy
+}
+
+//getXbrCoverages compute (by supersampling) the coverage of each sub pixel for each shape and corner
+func getXbrCoverages(factor int) *xbrCoverages {
+	const samples = 8
+
+	xbrCoveragesMutex.Lock()
+	defer xbrCoveragesMutex.Unlock()
+	if coverages, ok := xbrCoveragesByScale[factor]; ok {
+		return coverages
+	}
+	var coverages xbrCoverages
+	for shape := 0; shape < xbrNbShapes; shape++ {
+		for rotation := 0; rotation < 4; rotation++ {
+			coverages[shape][rotation] = make([]float64, factor*factor)
+			for sub := range coverages[shape][rotation] {
+				inside := 0
+				for sy := 0; sy < samples; sy++ {
+					for sx := 0; sx < samples; sx++ {
+						// sample position centered on the pixel, rotated back to the bottom right corner
+						x := (float64(sub%factor)+(float64(sx)+0.5)/samples)/float64(factor) - 0.5
+						y := (float64(sub/factor)+(float64(sy)+0.5)/samples)/float64(factor) - 0.5
+						for i := 0; i < (4-rotation)%4; i++ {
+							x, y = y, -x
+						}
+						if xbrInside(shape, x+0.5, y+0.5) {
+							inside++
+						}
+					}
+				}
+				coverages[shape][rotation][sub] = float64(inside) / (samples * samples)
+			}
+		}
+	}
+	xbrCoveragesByScale[factor] = &coverages
+	return &coverages
+}
+
+//xbrDistance distance between two colors
+func xbrDistance(c1 uint32, c2 uint32) int {
+	y1, u1, v1 := yuv(c1)
+	y2, u2, v2 := yuv(c2)
+	return abs(y1-y2) + abs(u1-u2) + abs(v1-v2)
+}
+
+func xbrEqual(c1 uint32, c2 uint32) bool {
+	return xbrDistance(c1, c2) < 155
+}
+
+//xbrCorner find the edge at one corner of a pixel and return its shape and the color behind it
+//the names are the ones of the bottom right corner, at the other corners the neighbourhood is rotated
+//         A1 B1 C1
+//      A0  A  B  C C4
+//      D0  D  E  F F4
+//      G0  G  H  I I4
+//         G5 H5 I5
+func xbrCorner(src *image.RGBA, x int, y int, rotation int) (int, uint32, bool) {
+	at := func(dx int, dy int) uint32 {
+		dx, dy = xbrRotate(dx, dy, rotation)
+		return pixelAt(src, x+dx, y+dy)
+	}
+	e, f, h, i := at(0, 0), at(1, 0), at(0, 1), at(1, 1)
+	if e == h || e == f {
+		return 0, 0, false
+	}
+	b, c, d, g := at(0, -1), at(1, -1), at(-1, 0), at(-1, 1)
+	f4, i4, h5, i5 := at(2, 0), at(2, 1), at(0, 2), at(1, 2)
+	df := xbrDistance
+
+	wd1 := df(e, c) + df(e, g) + df(i, h5) + df(i, f4) + 4*df(h, f)
+	wd2 := df(h, d) + df(h, i5) + df(f, i4) + df(f, b) + 4*df(e, i)
+	color := h
+	if df(e, f) <= df(e, h) {
+		color = f
+	}
+	if wd1 < wd2 && ((!xbrEqual(f, b) && !xbrEqual(h, d)) ||
+		(xbrEqual(e, i) && !xbrEqual(f, i4) && !xbrEqual(h, i5)) ||
+		xbrEqual(e, g) || xbrEqual(e, c)) {
+		ke := df(f, g)
+		ki := df(h, c)
+		ex2 := e != c && b != c
+		ex3 := e != g && d != g
+		shallow := ke*2 <= ki && ex3
+		steep := ke >= ki*2 && ex2
+		switch {
+		case shallow && steep:
+			return xbrLeftUp, color, true
+		case shallow:
+			return xbrLeft, color, true
+		case steep:
+			return xbrUp, color, true
+		}
+		return xbrEdge, color, true
+	}
+	return 0, 0, false
+}
+
+func xbr(src *image.RGBA, dst *image.RGBA, factor int, top int, bottom int) {
+	coverages := getXbrCoverages(factor)
+	block := make([]uint32, factor*factor)
+
+	for y := top; y < bottom; y++ {
+		for x := 0; x < src.Rect.Dx(); x++ {
+			e := pixelAt(src, x, y)
+			for i := range block {
+				block[i] = e
+			}
+			for rotation := 0; rotation < 4; rotation++ {
+				shape, color, found := xbrCorner(src, x, y, rotation)
+				if !found {
+					continue
+				}
+				for i, coverage := range coverages[shape][rotation] {
+					if coverage > 0 {
+						block[i] = mix(block[i], color, coverage)
+					}
+				}
+			}
+			for i, color := range block {
+				setPixel(dst, x*factor+i%factor, y*factor+i/factor, color)
+			}
+		}
+	}
+}
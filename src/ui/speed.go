@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/hadi-ilies/MyNesEmulator/src/constant"
+	"github.com/hadi-ilies/MyNesEmulator/src/nes"
+)
+
+//slow motion speeds, cycled by the slowmotion hotkey
+var slowMotionSpeeds = []float64{1, 0.5, 0.25}
+
+//time spent emulating per displayed frame when fast forward is uncapped, so the window keeps up
+const uncappedBudget = 0.015
+
+//emulationSpeed says how fast the emulation runs compared to a real console
+type emulationSpeed struct {
+	paused      bool
+	advance     int // frames to run while paused
+	fastForward bool
+	fastRate    float64 // fast forward speed, 0 for as fast as possible
+	slowMotion  int     // index in slowMotionSpeeds
+}
+
+//String describe the speed in the window title
+func (speed *emulationSpeed) String() string {
+	switch {
+	case speed.paused:
+		return "paused"
+	case speed.fastForward && speed.fastRate == 0:
+		return "fast forward"
+	case speed.fastForward:
+		return fmt.Sprintf("fast forward x%g", speed.fastRate)
+	case slowMotionSpeeds[speed.slowMotion] != 1:
+		return fmt.Sprintf("slow motion x%g", slowMotionSpeeds[speed.slowMotion])
+	}
+	return ""
+}
+
+//onSpeedHotkey apply a speed hotkey, false if the action is not one
+func (view *GameView) onSpeedHotkey(action string) bool {
+	speed := &view.speed
+
+	switch action {
+	case "pause":
+		speed.paused = !speed.paused
+	case "advance": // run a single frame, pausing first if needed
+		speed.paused = true
+		speed.advance++
+	case "fastforward":
+		speed.fastForward = !speed.fastForward
+	case "slowmotion":
+		speed.slowMotion = (speed.slowMotion + 1) % len(slowMotionSpeeds)
+	default:
+		return false
+	}
+	if speed.paused { // the frames run while paused are silent, the sound already queued must not play on
+		view.flushSound()
+	}
+	title := constant.UITitle
+	if status := speed.String(); status != "" {
+		title += " - " + status
+	}
+	view.ui.GetWindow().SetTitle(title)
+	return true
+}
+
+//run emulate the time elapsed since the last update in whole frames, at the chosen speed
+func (view *GameView) run(dt float64) {
+	speed := &view.speed
+
+	switch {
+	case speed.paused:
+		view.sound(0)
+		for ; speed.advance > 0; speed.advance-- {
+			view.stepFrame()
+		}
+	case speed.fastForward && speed.fastRate == 0:
+		view.sound(0)
+		start := glfw.GetTime()
+		for glfw.GetTime()-start < uncappedBudget {
+			view.stepFrame()
+		}
+	case speed.fastForward:
+		view.sound(0)
+		view.runFrames(dt * speed.fastRate)
+	case slowMotionSpeeds[speed.slowMotion] != 1:
+		view.sound(slowMotionSpeeds[speed.slowMotion])
+		view.runFrames(dt * slowMotionSpeeds[speed.slowMotion])
+	case view.syncedToRefresh():
+		// one frame per refresh, the dynamic rate control absorbs the small difference of rate
+		view.sound(1)
+		view.frameTime = 0
+		view.stepFrame()
+	default:
+		view.sound(1)
+		view.runFrames(dt)
+	}
+}
+
+//runFrames run as many whole frames as fit in the emulated time, the rest is kept for the next update
+func (view *GameView) runFrames(seconds float64) {
+	view.frameTime += seconds
+	for view.frameTime >= 1/nes.FrameRate {
+		view.stepFrame()
+		view.frameTime -= 1 / nes.FrameRate
+	}
+}
+
+//stepFrame run one frame of the console, recorded when a gif is being recorded: the gif follows the emulated frames,
+//whatever the speed and the refresh rate
+func (view *GameView) stepFrame() {
+	view.nes.StepFrame()
+	if view.recording {
+		view.record()
+	}
+}
+
+//syncedToRefresh tell whether the frames are shown on a vsynced monitor refreshing at the rate of the console (within 1%)
+func (view *GameView) syncedToRefresh() bool {
+	refresh := view.ui.refresh
+
+	return view.ui.vsync && refresh > 0 && refresh > nes.FrameRate*0.99 && refresh < nes.FrameRate*1.01
+}
+
+//sound turn the sound on at the given speed, 0 to mute it: slow motion lowers the pitch,
+//the apu sample rate follows the dynamic rate control of the audio buffer
+func (view *GameView) sound(speed float64) {
+	audio := view.ui.audio
+
+	if audio == nil || speed == 0 {
+		view.nes.SetAudioChannel(nil)
+		return
+	}
+	view.nes.SetAudioChannel(audio.channel)
+	view.nes.SetSampleRate(audio.SampleRate() / speed)
+}
+
+//flushSound drop the samples waiting to be played, so they do not keep playing once the emulation stops
+func (view *GameView) flushSound() {
+	if view.ui.audio != nil {
+		view.ui.audio.Flush()
+	}
+}
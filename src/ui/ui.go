@@ -3,7 +3,8 @@ package ui
 import (
 	//	"os"
 
-	"github.com/go-gl/gl/v2.1/gl"
+	oglEncap "./openglencapsulation"
+	"github.com/go-gl/gl/v3.3-core/gl"
 	"github.com/go-gl/glfw/v3.3/glfw"
 	"github.com/hadi-ilies/MyNesEmulator/src/nes"
 )
@@ -13,12 +14,23 @@ type Ui struct {
 	window     *glfw.Window
 	actualView View
 	timestamp  float64
+	options    Options            // settings given by the user
+	renderer   *oglEncap.Renderer // draws the frames in the window
+	controls   *Controls          // keyboard and gamepad bindings
+	audio      *Audio             // nil when no sound device could be opened
+	vsync      bool               // the buffers are swapped on the refresh of the monitor
+	refresh    float64            // refresh rate of the monitor, 0 if unknown
 }
 
 //NewUI is the constructor of my ui
-func NewUI(width int, height int, uiTitle string) *Ui {
+func NewUI(width int, height int, uiTitle string, options Options) *Ui {
 	var ui Ui
 
+	//ask for an opengl 3.3 core context, forward compatible for macos
+	glfw.WindowHint(glfw.ContextVersionMajor, 3)
+	glfw.WindowHint(glfw.ContextVersionMinor, 3)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
 	//create and Init window
 	window, err := glfw.CreateWindow(width, height, uiTitle, nil, nil)
 
@@ -26,9 +38,18 @@ func NewUI(width int, height int, uiTitle string) *Ui {
 		println("print usage and error")
 	}
 	window.MakeContextCurrent()
+	//wait for the monitor refresh before swapping, no tearing
+	if options.Vsync {
+		glfw.SwapInterval(1)
+	}
+	if monitor := glfw.GetPrimaryMonitor(); monitor != nil {
+		ui.refresh = float64(monitor.GetVideoMode().RefreshRate)
+	}
 
 	ui.window = window
 	ui.timestamp = 0
+	ui.options = options
+	ui.vsync = options.Vsync
 	return &ui
 }
 
@@ -49,10 +70,8 @@ func (ui *Ui) getInView(view View) {
 }
 
 //playGame
-func (ui *Ui) loadGame(gamePath string) {
-	var nes nes.Nes = nes.NewNes(gamePath)
-
-	ui.getInView(NewGameView(ui, &nes))
+func (ui *Ui) loadGame(console *nes.Nes) {
+	ui.getInView(NewGameView(ui, console))
 }
 
 func (ui *Ui) displayView() {
@@ -65,9 +84,9 @@ func (ui *Ui) displayView() {
 }
 
 //start UI it is the main loop
-func (ui *Ui) Run(gamePath string) {
+func (ui *Ui) Run(console *nes.Nes) {
 	//load the emulator and views
-	ui.loadGame(gamePath)
+	ui.loadGame(console)
 	//main loop
 	for !ui.window.ShouldClose() {
 		// clear screen at each loop's turn.
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+
+	"./scalers"
+
+	"github.com/hadi-ilies/MyNesEmulator/src/nes/nescomponents"
+)
+
+//ntsc filter modes the user can switch between with the N key
+var ntscModes = []string{"", "composite", "svideo", "rgb"}
+
+//newNtscFilter create the filter of a mode, nil means no filter
+func newNtscFilter(mode string, options Options) (*nescomponents.NtscFilter, error) {
+	switch mode {
+	case "":
+		return nil, nil
+	case "composite":
+		return nescomponents.NewNtscFilter(nescomponents.NtscComposite, options.NtscPalette), nil
+	case "svideo":
+		return nescomponents.NewNtscFilter(nescomponents.NtscSVideo, options.NtscPalette), nil
+	case "rgb":
+		return nescomponents.NewNtscFilter(nescomponents.NtscRGB, options.NtscPalette), nil
+	}
+	return nil, fmt.Errorf("unknown ntsc filter: %s", mode)
+}
+
+//nextNtscMode return the mode that follows the given one
+func nextNtscMode(mode string) string {
+	for i, m := range ntscModes {
+		if m == mode {
+			return ntscModes[(i+1)%len(ntscModes)]
+		}
+	}
+	return ntscModes[0]
+}
+
+//newScaler create the software scaler of a mode, nil means no scaler
+func newScaler(mode string) (*scalers.Scaler, error) {
+	if mode == "" {
+		return nil, nil
+	}
+	return scalers.New(mode)
+}
+
+//nextScalerMode return the scaler that follows the given one, "" (none) follows the last one
+func nextScalerMode(mode string) string {
+	modes := append([]string{""}, scalers.Names...)
+	for i, m := range modes {
+		if m == mode {
+			return modes[(i+1)%len(modes)]
+		}
+	}
+	return ""
+}
+
+//frame return the image to display, filtered and scaled if the user asked for it
+func (view *GameView) frame() *image.RGBA {
+	frame := view.nes.PixelBuffer()
+	if view.ntscFilter != nil {
+		frame = view.ntscFilter.Apply(view.nes.IndexBuffer(), view.nes.GetComponents().GetPpu().Frame)
+	}
+	if view.scaler != nil {
+		frame = view.scaler.Apply(frame)
+	}
+	return frame
+}
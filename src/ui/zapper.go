@@ -0,0 +1,29 @@
+package ui
+
+import (
+	oglEncap "./openglencapsulation"
+	"github.com/go-gl/glfw/v3.3/glfw"
+	"github.com/hadi-ilies/MyNesEmulator/src/nes/nescomponents"
+)
+
+//cursorOnFrame return where the mouse is on the picture, in nes pixels, false when it is outside
+func cursorOnFrame(window *glfw.Window, display oglEncap.Display) (int, int, bool) {
+	windowWidth, windowHeight := window.GetSize()
+	bufferWidth, bufferHeight := window.GetFramebufferSize()
+	cursorX, cursorY := window.GetCursorPos()
+
+	if windowWidth <= 0 || windowHeight <= 0 {
+		return -1, -1, false
+	}
+	// the cursor is in screen coordinates, the picture is drawn in framebuffer pixels (they differ on hidpi screens)
+	x := int(cursorX * float64(bufferWidth) / float64(windowWidth))
+	y := int(cursorY * float64(bufferHeight) / float64(windowHeight))
+	return display.ToFrame(x, y, bufferWidth, bufferHeight)
+}
+
+//aimZapper aim the zapper where the mouse is on the picture, the left button pulls the trigger
+func aimZapper(window *glfw.Window, zapper *nescomponents.Zapper, display oglEncap.Display) {
+	x, y, _ := cursorOnFrame(window, display)
+	zapper.Aim(x, y)
+	zapper.SetTrigger(window.GetMouseButton(glfw.MouseButtonLeft) == glfw.Press)
+}